@@ -5,6 +5,7 @@ import (
     "log"
     "os"
 
+    "github.com/PragaL15/med_admin_backend/src/clinic"
     "github.com/joho/godotenv"
     "gorm.io/driver/postgres"
     "gorm.io/gorm"
@@ -27,13 +28,20 @@ func InitializeDB() (*gorm.DB, error) {
     log.Println("DB_PORT:", os.Getenv("DB_PORT"))
     log.Println("DB_NAME:", os.Getenv("DB_NAME"))
 
+    // Sessions run in the clinic time zone, so bare dates compared with
+    // timestamps mean clinic days.
+    if err := clinic.Load(); err != nil {
+        return nil, err
+    }
+
     // Construct the database connection URL
-    dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+    dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=%s",
         os.Getenv("DB_HOST"),
         os.Getenv("DB_USER"),
         os.Getenv("DB_PASSWORD"),
         os.Getenv("DB_NAME"),
-        os.Getenv("DB_PORT"))
+        os.Getenv("DB_PORT"),
+        clinic.Location())
 
     // Open a connection to the database using GORM
    
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a schema change applied once, inside a transaction, and
+// recorded in schema_migrations. IDs are applied in slice order and must
+// never be renamed or reordered once released.
+type Migration struct {
+	ID string
+	Up func(tx *gorm.DB) error
+}
+
+type schemaMigration struct {
+	ID        string    `gorm:"primaryKey;column:id"`
+	AppliedAt time.Time `gorm:"column:applied_at;autoCreateTime"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrate applies every migration in migrations that has not run yet.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("error creating schema_migrations: %v", err)
+	}
+
+	var applied []string
+	if err := db.Model(&schemaMigration{}).Pluck("id", &applied).Error; err != nil {
+		return fmt.Errorf("error reading schema_migrations: %v", err)
+	}
+	done := make(map[string]bool, len(applied))
+	for _, id := range applied {
+		done[id] = true
+	}
+
+	for _, m := range migrations {
+		if done[m.ID] {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{ID: m.ID}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %v", m.ID, err)
+		}
+		log.Printf("Applied migration %s", m.ID)
+	}
+	return nil
+}
+
+// execAll returns an Up func that runs the statements in order.
+func execAll(statements ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,304 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/mrn"
+	"gorm.io/gorm"
+)
+
+// statusList is the appointment lifecycle as an SQL list.
+var statusList = "('" + strings.Join(models.AppointmentStatuses, "', '") + "')"
+
+var migrations = []Migration{
+	{
+		ID: "0001_patient_search",
+		Up: execAll(
+			`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+			`CREATE EXTENSION IF NOT EXISTS fuzzystrmatch`,
+			`CREATE INDEX IF NOT EXISTS patient_name_trgm_idx ON patient_id USING gin (p_name gin_trgm_ops)`,
+			`CREATE INDEX IF NOT EXISTS patient_email_trgm_idx ON patient_id USING gin (p_email gin_trgm_ops)`,
+			`CREATE INDEX IF NOT EXISTS patient_address_trgm_idx ON patient_id USING gin (p_address gin_trgm_ops)`,
+			`CREATE INDEX IF NOT EXISTS patient_number_digits_trgm_idx ON patient_id
+				USING gin ((regexp_replace(p_number, '\D', '', 'g')) gin_trgm_ops)`,
+			`CREATE INDEX IF NOT EXISTS patient_name_dmetaphone_idx ON patient_id (dmetaphone(p_name))`,
+			`CREATE INDEX IF NOT EXISTS patient_search_tsv_idx ON patient_id USING gin (
+				to_tsvector('simple', coalesce(p_name, '') || ' ' || coalesce(p_email, '') || ' ' || coalesce(p_address, '')))`,
+		),
+	},
+	{
+		ID: "0002_patient_duplicates",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec(`ALTER TABLE patient_id ADD COLUMN IF NOT EXISTS merged_into_p_id integer`).Error; err != nil {
+				return err
+			}
+			return tx.AutoMigrate(&models.DuplicateCandidate{}, &models.PatientMerge{})
+		},
+	},
+	{
+		ID: "0003_patient_identifiers",
+		Up: func(tx *gorm.DB) error {
+			err := execAll(
+				`CREATE SEQUENCE IF NOT EXISTS patient_p_id_seq OWNED BY patient_id.p_id`,
+				`SELECT setval('patient_p_id_seq', COALESCE((SELECT MAX(p_id) FROM patient_id), 0) + 1, false)`,
+				`ALTER TABLE patient_id ALTER COLUMN p_id SET DEFAULT nextval('patient_p_id_seq')`,
+				`CREATE SEQUENCE IF NOT EXISTS patient_mrn_seq`,
+				`ALTER TABLE patient_id ADD COLUMN IF NOT EXISTS mrn varchar(64)`,
+			)(tx)
+			if err != nil {
+				return err
+			}
+			if err := backfillMRNs(tx); err != nil {
+				return err
+			}
+			if err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_id_mrn ON patient_id (mrn)`).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(`CREATE INDEX IF NOT EXISTS patient_mrn_upper_idx ON patient_id (upper(mrn))`).Error; err != nil {
+				return err
+			}
+			return tx.AutoMigrate(&models.PatientIdentifier{})
+		},
+	},
+	{
+		ID: "0004_patient_intake",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.PatientContact{}, &models.PatientInsurance{}, &models.PatientConsent{})
+		},
+	},
+	{
+		// Adds the relationship, authorisation and link columns to
+		// patient_contacts.
+		ID: "0005_patient_contacts",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.PatientContact{})
+		},
+	},
+	{
+		ID: "0006_patient_clinical",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.PatientAllergy{}, &models.PatientProblem{})
+		},
+	},
+	{
+		ID: "0007_vital_signs",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.VitalSign{})
+		},
+	},
+	{
+		// Replaces the granted flag with a status and adds scope, witness,
+		// document and withdrawal columns.
+		ID: "0008_patient_consents",
+		Up: func(tx *gorm.DB) error {
+			legacy := tx.Migrator().HasColumn("patient_consents", "granted")
+			if legacy {
+				// given_at is NOT NULL, so it has to be filled before
+				// AutoMigrate sees it.
+				err := execAll(
+					`ALTER TABLE patient_consents ADD COLUMN IF NOT EXISTS given_at timestamptz`,
+					`UPDATE patient_consents SET given_at = recorded_at WHERE given_at IS NULL`,
+					`ALTER TABLE patient_consents ALTER COLUMN given_at SET NOT NULL`,
+				)(tx)
+				if err != nil {
+					return err
+				}
+			}
+			if err := tx.AutoMigrate(&models.PatientConsent{}); err != nil {
+				return err
+			}
+			if !legacy {
+				return nil
+			}
+			return execAll(
+				`UPDATE patient_consents SET status = 'refused' WHERE granted = false`,
+				`ALTER TABLE patient_consents DROP COLUMN granted`,
+			)(tx)
+		},
+	},
+	{
+		ID: "0009_documents",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Document{})
+		},
+	},
+	{
+		ID: "0010_appointment_changes",
+		Up: func(tx *gorm.DB) error {
+			err := execAll(
+				`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS duration_minutes integer NOT NULL DEFAULT 15`,
+				`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS cancel_reason text`,
+				`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS cancelled_at timestamptz`,
+				`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS cancelled_by integer`,
+				`CREATE INDEX IF NOT EXISTS appointments_d_id_app_date_idx ON appointments (d_id, app_date)`,
+				`CREATE INDEX IF NOT EXISTS appointments_p_id_app_date_idx ON appointments (p_id, app_date)`,
+			)(tx)
+			if err != nil {
+				return err
+			}
+			return tx.AutoMigrate(&models.AppointmentChange{})
+		},
+	},
+	{
+		// Moves legacy statuses onto the lifecycle: "scheduled" and blank
+		// bookings were already confirmed by the front desk.
+		ID: "0011_appointment_status",
+		Up: execAll(
+			`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS status_changed_at timestamptz`,
+			`UPDATE appointments SET appo_status = lower(trim(appo_status)) WHERE appo_status IS NOT NULL`,
+			`UPDATE appointments SET appo_status = 'confirmed' WHERE appo_status IS NULL OR appo_status IN ('', 'scheduled')`,
+			`UPDATE appointments SET appo_status = 'no-show' WHERE appo_status IN ('no_show', 'noshow')`,
+			`UPDATE appointments SET status_changed_at = COALESCE(updated_at, created_at) WHERE status_changed_at IS NULL`,
+		),
+	},
+	{
+		ID: "0012_doctor_schedules",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.DoctorSchedule{}, &models.DoctorScheduleOverride{}, &models.DoctorLeave{}); err != nil {
+				return err
+			}
+			return tx.Exec(`CREATE INDEX IF NOT EXISTS doctor_leave_range_idx ON doctor_leave (d_id, starts_on, ends_on)`).Error
+		},
+	},
+	{
+		ID: "0013_appointment_series",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.AppointmentSeries{}); err != nil {
+				return err
+			}
+			return execAll(
+				`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS series_id integer REFERENCES appointment_series (id)`,
+				`CREATE INDEX IF NOT EXISTS appointments_series_id_idx ON appointments (series_id)`,
+			)(tx)
+		},
+	},
+	{
+		ID: "0014_waitlist",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.WaitlistEntry{}, &models.WaitlistOffer{})
+		},
+	},
+	{
+		ID: "0015_notifications",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Notification{})
+		},
+	},
+	{
+		ID: "0016_calendar_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.CalendarToken{})
+		},
+	},
+	{
+		// Check-in and queue columns. Walk-ins are appointments booked at
+		// check-in; tokens count up per doctor per day.
+		ID: "0017_appointment_queue",
+		Up: execAll(
+			`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS walk_in boolean NOT NULL DEFAULT false`,
+			`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS checked_in_at timestamptz`,
+			`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS queue_token integer`,
+			`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS queue_priority integer`,
+			`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS called_at timestamptz`,
+			`CREATE INDEX IF NOT EXISTS appointments_queue_idx ON appointments (d_id, checked_in_at) WHERE checked_in_at IS NOT NULL`,
+		),
+	},
+	{
+		// Replaces the date and time columns, clinic wall-clock times without
+		// a zone, with starts_at and ends_at instants. Instants derived from
+		// the old columns were stored as if the wall clock were UTC; they are
+		// moved to the same wall-clock time in the clinic time zone.
+		ID: "0018_appointment_instants",
+		Up: func(tx *gorm.DB) error {
+			zone := clinic.Location().String()
+			shift := func(table, column, where string) string {
+				return `UPDATE ` + table + ` SET ` + column + ` = (` + column + ` AT TIME ZONE 'UTC') AT TIME ZONE @zone WHERE ` + column + ` IS NOT NULL` + where
+			}
+			statements := []string{
+				`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS starts_at timestamptz`,
+				`ALTER TABLE appointments ADD COLUMN IF NOT EXISTS ends_at timestamptz`,
+				`UPDATE appointments SET starts_at = (app_date::date + COALESCE(NULLIF(time::text, ''), '00:00')::time) AT TIME ZONE @zone WHERE starts_at IS NULL`,
+				`UPDATE appointments SET ends_at = starts_at + duration_minutes * interval '1 minute' WHERE ends_at IS NULL`,
+				`ALTER TABLE appointments ALTER COLUMN starts_at SET NOT NULL, ALTER COLUMN ends_at SET NOT NULL`,
+				`DROP INDEX IF EXISTS appointments_d_id_app_date_idx`,
+				`DROP INDEX IF EXISTS appointments_p_id_app_date_idx`,
+				`CREATE INDEX IF NOT EXISTS appointments_d_id_starts_at_idx ON appointments (d_id, starts_at)`,
+				`CREATE INDEX IF NOT EXISTS appointments_p_id_starts_at_idx ON appointments (p_id, starts_at)`,
+				`ALTER TABLE appointments DROP COLUMN app_date, DROP COLUMN time`,
+				shift("appointment_changes", "from_start", ""),
+				shift("appointment_changes", "to_start", ""),
+				shift("appointment_series", "first_start", ""),
+				shift("appointment_series", "cancelled_from", ""),
+				shift("waitlist_offers", "starts_at", ""),
+				// Reminders are keyed by start minus offset; shifting them
+				// with the starts keeps them from being sent again.
+				shift("notifications", "due_at", ` AND kind = '`+models.NotificationReminder+`'`),
+			}
+			for _, sql := range statements {
+				var args []interface{}
+				if strings.Contains(sql, "@zone") {
+					args = append(args, map[string]interface{}{"zone": zone})
+				}
+				if err := tx.Exec(sql, args...).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// 0011 mapped the common legacy statuses; this maps the remaining
+		// free text from the old booking form onto the lifecycle and then
+		// keeps it there. Anything unrecognised was a booking the front desk
+		// had taken, so it is confirmed, as blank statuses were.
+		ID: "0019_appointment_status_check",
+		Up: execAll(
+			`UPDATE appointments SET appo_status = lower(trim(appo_status)) WHERE appo_status <> lower(trim(appo_status))`,
+			`UPDATE appointments SET appo_status = 'requested' WHERE appo_status IN ('pending', 'request')`,
+			`UPDATE appointments SET appo_status = 'checked-in' WHERE appo_status IN ('checked in', 'checked_in', 'checkedin', 'arrived')`,
+			`UPDATE appointments SET appo_status = 'in-consultation' WHERE appo_status IN ('in consultation', 'in_consultation', 'in progress', 'in-progress', 'in_progress')`,
+			`UPDATE appointments SET appo_status = 'completed' WHERE appo_status IN ('complete', 'done', 'attended', 'finished', 'closed')`,
+			`UPDATE appointments SET appo_status = 'cancelled' WHERE appo_status IN ('canceled', 'cancel')`,
+			`UPDATE appointments SET appo_status = 'no-show' WHERE appo_status IN ('no show', 'missed', 'absent')`,
+			`UPDATE appointments SET appo_status = 'confirmed' WHERE appo_status IS NULL OR appo_status NOT IN `+statusList,
+			`ALTER TABLE appointments ALTER COLUMN appo_status SET NOT NULL`,
+			`ALTER TABLE appointments DROP CONSTRAINT IF EXISTS appointments_appo_status_check`,
+			`ALTER TABLE appointments ADD CONSTRAINT appointments_appo_status_check CHECK (appo_status IN `+statusList+`)`,
+		),
+	},
+	{
+		ID: "0020_appointment_change_fields",
+		Up: execAll(
+			`ALTER TABLE appointment_changes ADD COLUMN IF NOT EXISTS fields jsonb`,
+		),
+	},
+}
+
+// backfillMRNs gives existing patients an MRN in p_id order, using their
+// registration year.
+func backfillMRNs(tx *gorm.DB) error {
+	var rows []struct {
+		PID       uint      `gorm:"column:p_id"`
+		CreatedAt time.Time `gorm:"column:createdat"`
+	}
+	if err := tx.Table("patient_id").Select("p_id, createdat").Where("mrn IS NULL").Order("p_id").Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		var seq int64
+		if err := tx.Raw(mrn.SequenceSQL).Scan(&seq).Error; err != nil {
+			return err
+		}
+		value, err := mrn.Render(seq, row.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if err := tx.Table("patient_id").Where("p_id = ?", row.PID).Update("mrn", value).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
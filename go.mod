@@ -4,47 +4,87 @@ go 1.22.2
 
 require (
 	github.com/andybalholm/brotli v1.0.5
-	github.com/felixge/httpsnoop v1.0.3 
-	github.com/gofiber/cors v0.2.2 
+	github.com/felixge/httpsnoop v1.0.4
+	github.com/go-playground/validator/v10 v10.22.1
+	github.com/gofiber/cors v0.2.2
 	github.com/gofiber/fiber v1.13.3
-	github.com/gofiber/fiber/v2 v2.52.5 
+	github.com/gofiber/fiber/v2 v2.52.5
 	github.com/gofiber/utils v0.0.9
-	github.com/golang-jwt/jwt v3.2.2+incompatible 
-	github.com/golang-jwt/jwt/v4 v4.5.1 
-	github.com/google/uuid v1.5.0 
-	github.com/gorilla/context v1.1.2 
+	github.com/golang-jwt/jwt v3.2.2+incompatible
+	github.com/golang-jwt/jwt/v4 v4.5.1
+	github.com/google/uuid v1.6.0
+	github.com/gorilla/context v1.1.2
 	github.com/gorilla/handlers v1.5.2
-	github.com/gorilla/mux v1.8.1 
-	github.com/gorilla/schema v1.1.0 
-	github.com/jackc/chunkreader/v2 v2.0.1 
-	github.com/jackc/pgconn v1.14.3 
-	github.com/jackc/pgio v1.0.0 
-	github.com/jackc/pgpassfile v1.0.0 
-	github.com/jackc/pgproto3/v2 v2.3.3 
-	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a 
-	github.com/jackc/pgtype v1.14.0 
-	github.com/jackc/pgx/v4 v4.18.3 
-	github.com/jackc/pgx/v5 v5.5.5 
+	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/schema v1.1.0
+	github.com/jackc/chunkreader/v2 v2.0.1
+	github.com/jackc/pgconn v1.14.3
+	github.com/jackc/pgio v1.0.0
+	github.com/jackc/pgpassfile v1.0.0
+	github.com/jackc/pgproto3/v2 v2.3.3
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a
+	github.com/jackc/pgtype v1.14.0
+	github.com/jackc/pgx/v4 v4.18.3
+	github.com/jackc/pgx/v5 v5.5.5
+	github.com/jackc/puddle/v2 v2.2.1
+	github.com/jinzhu/gorm v1.9.16
+	github.com/jinzhu/now v1.1.5
+	github.com/joho/godotenv v1.5.1
+	github.com/klauspost/compress v1.17.11
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-colorable v0.1.13
+	github.com/mattn/go-runewidth v0.0.15
+	github.com/minio/minio-go/v7 v7.0.80
+	github.com/prometheus/client_golang v1.20.5
+	github.com/rs/cors v1.11.1
+	github.com/valyala/bytebufferpool v1.0.0
+	github.com/valyala/fasthttp v1.51.0
+	go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux v0.53.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.28.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+	golang.org/x/crypto v0.29.0
+	golang.org/x/sync v0.9.0
+	golang.org/x/sys v0.27.0
+	golang.org/x/text v0.20.0
+	gorm.io/driver/postgres v1.5.9
+	gorm.io/gorm v1.25.12
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/gabriel-vasile/mimetype v1.4.3 // indirect
+	github.com/go-ini/ini v1.67.0 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/go-playground/locales v0.14.1 // indirect
+	github.com/go-playground/universal-translator v0.18.1 // indirect
+	github.com/goccy/go-json v0.10.3 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.20.0 // indirect
 	github.com/jackc/puddle v1.3.0 //indirect
-	github.com/jackc/puddle/v2 v2.2.1 
-	github.com/jinzhu/gorm v1.9.16 
 	github.com/jinzhu/inflection v1.0.0 //indirect
-	github.com/jinzhu/now v1.1.5 
-	github.com/joho/godotenv v1.5.1 
-	github.com/klauspost/compress v1.17.0 
-	github.com/lib/pq v1.10.9 
-	github.com/mattn/go-colorable v0.1.13 
+	github.com/klauspost/cpuid/v2 v2.2.8 // indirect
+	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 //indirect
-	github.com/mattn/go-runewidth v0.0.15 
+	github.com/minio/md5-simd v1.1.2 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.55.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	github.com/rs/cors v1.11.1 
-	github.com/valyala/bytebufferpool v1.0.0 
-	github.com/valyala/fasthttp v1.51.0 
+	github.com/rs/xid v1.6.0 // indirect
 	github.com/valyala/tcplisten v1.0.0 // indirect
-	golang.org/x/crypto v0.29.0 
-	golang.org/x/sync v0.9.0 
-	golang.org/x/sys v0.27.0 
-	golang.org/x/text v0.20.0 
-	gorm.io/driver/postgres v1.5.9 //indirect
-	gorm.io/gorm v1.25.12 
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.28.0 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	go.opentelemetry.io/proto/otlp v1.3.1 // indirect
+	golang.org/x/net v0.30.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20240701130421-f6361c86f094 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240701130421-f6361c86f094 // indirect
+	google.golang.org/grpc v1.64.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
 )
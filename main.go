@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 
 	"github.com/PragaL15/med_admin_backend/database"
+	"github.com/PragaL15/med_admin_backend/src/metrics"
+	"github.com/PragaL15/med_admin_backend/src/notify"
 	"github.com/PragaL15/med_admin_backend/src/routers/user"
+	"github.com/PragaL15/med_admin_backend/src/services/notifications"
+	"github.com/PragaL15/med_admin_backend/src/services/waitlist"
+	"github.com/PragaL15/med_admin_backend/src/storage"
+	"github.com/PragaL15/med_admin_backend/src/tracing"
 	"github.com/gorilla/handlers"
 
 )
@@ -25,7 +32,40 @@ func main() {
 		}
 	}()
 
-	router := routers.SetupRoutes(db)
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Printf("Failed to shut down tracing: %v", err)
+		}
+	}()
+	if err := tracing.RegisterGORM(db); err != nil {
+		log.Fatalf("Failed to register tracing callbacks: %v", err)
+	}
+
+	if err := metrics.Register(db); err != nil {
+		log.Fatalf("Failed to register metrics: %v", err)
+	}
+	waitlist.Register(context.Background(), db)
+
+	channels, err := notify.New()
+	if err != nil {
+		log.Fatalf("Failed to initialize notification channels: %v", err)
+	}
+	notifications.Register(context.Background(), db, channels)
+
+	store, err := storage.New(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to initialize document storage: %v", err)
+	}
+
+	router := routers.SetupRoutes(db, store)
 
 	corsOrigin := handlers.AllowedOrigins([]string{"http://localhost:5173"}) 
 	corsMethods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}) 
@@ -0,0 +1,109 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+const ContentType = "application/problem+json"
+
+// FieldError describes a single invalid field in a request payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is the error type returned to API clients. Cause is logged but never
+// serialised, so database and driver errors do not leak into responses.
+type Error struct {
+	Code   Code
+	Detail string
+	Fields []FieldError
+	Cause  error
+}
+
+func New(code Code, detail string) *Error {
+	return &Error{Code: code, Detail: detail}
+}
+
+func Wrap(code Code, detail string, cause error) *Error {
+	return &Error{Code: code, Detail: detail, Cause: cause}
+}
+
+func Validation(fields ...FieldError) *Error {
+	return &Error{Code: CodeValidationFailed, Detail: "One or more fields are invalid", Fields: fields}
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+func (e *Error) Status() int {
+	return lookup(e.Code).status
+}
+
+// problem is the RFC 7807 body, extended with code and errors members.
+type problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     Code         `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Write renders err as application/problem+json. Errors that are not an
+// *Error are reported as internal errors with a generic detail.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		apiErr = Wrap(CodeInternal, "", err)
+	}
+
+	e := lookup(apiErr.Code)
+	if e.status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, apiErr)
+	}
+
+	body := problem{
+		Type:     "/problems/" + string(apiErr.Code),
+		Title:    e.title,
+		Status:   e.status,
+		Detail:   apiErr.Detail,
+		Instance: r.URL.Path,
+		Code:     apiErr.Code,
+		Errors:   apiErr.Fields,
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(e.status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error encoding problem response: %v", err)
+	}
+}
+
+// NotFoundHandler and MethodNotAllowedHandler let the router report unmatched
+// requests in the same format as the handlers.
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, New(CodeNotFound, "No route matches "+r.URL.Path))
+	})
+}
+
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, r, New(CodeMethodNotAllowed, r.Method+" is not supported on "+r.URL.Path))
+	})
+}
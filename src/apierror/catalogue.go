@@ -0,0 +1,49 @@
+package apierror
+
+import "net/http"
+
+// Code is a stable, machine readable error identifier. Clients should branch
+// on Code rather than on the human readable title or detail.
+type Code string
+
+const (
+	CodeInvalidJSON        Code = "invalid_json"
+	CodeInvalidParameter   Code = "invalid_parameter"
+	CodeValidationFailed   Code = "validation_failed"
+	CodeUnauthorized       Code = "unauthorized"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeAccountInactive    Code = "account_inactive"
+	CodeForbidden          Code = "forbidden"
+	CodeConsentRequired    Code = "consent_required"
+	CodeNotFound           Code = "not_found"
+	CodeMethodNotAllowed   Code = "method_not_allowed"
+	CodeConflict           Code = "conflict"
+	CodeInternal           Code = "internal_error"
+)
+
+type entry struct {
+	status int
+	title  string
+}
+
+var catalogue = map[Code]entry{
+	CodeInvalidJSON:        {http.StatusBadRequest, "Request body is not valid JSON"},
+	CodeInvalidParameter:   {http.StatusBadRequest, "Invalid request parameter"},
+	CodeValidationFailed:   {http.StatusUnprocessableEntity, "Request validation failed"},
+	CodeUnauthorized:       {http.StatusUnauthorized, "Authentication required"},
+	CodeInvalidCredentials: {http.StatusUnauthorized, "Invalid username or password"},
+	CodeAccountInactive:    {http.StatusUnauthorized, "Account is inactive"},
+	CodeForbidden:          {http.StatusForbidden, "Not authorized for this resource"},
+	CodeConsentRequired:    {http.StatusForbidden, "Patient has not consented to this action"},
+	CodeNotFound:           {http.StatusNotFound, "Resource not found"},
+	CodeMethodNotAllowed:   {http.StatusMethodNotAllowed, "Method not allowed"},
+	CodeConflict:           {http.StatusConflict, "Request conflicts with current state"},
+	CodeInternal:           {http.StatusInternalServerError, "Internal server error"},
+}
+
+func lookup(code Code) entry {
+	if e, ok := catalogue[code]; ok {
+		return e
+	}
+	return catalogue[CodeInternal]
+}
@@ -0,0 +1,86 @@
+// Package clinic holds clinic-wide settings: the time zone appointments are
+// booked in, and how dates and times are shown to people.
+package clinic
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	// Embed the zone database so CLINIC_TIMEZONE works on hosts without
+	// one, such as scratch containers.
+	_ "time/tzdata"
+)
+
+// Layouts for dates and times shown to people: on the dashboard, in
+// messages and in error details. The API itself exchanges RFC 3339.
+const (
+	DateLayout  = "02-01-2006"
+	ClockLayout = "03:04 PM"
+)
+
+var location = time.UTC
+
+// Load sets the clinic time zone from CLINIC_TIMEZONE, an IANA name such as
+// "Asia/Kolkata". Without it the clinic runs on UTC. "Local" is refused: the
+// name is also handed to Postgres, which does not know it, and the clinic's
+// zone should not depend on the host's. Call it once at startup, before
+// connecting to the database.
+func Load() error {
+	name := strings.TrimSpace(os.Getenv("CLINIC_TIMEZONE"))
+	if name == "" {
+		location = time.UTC
+		return nil
+	}
+	if strings.EqualFold(name, "Local") {
+		return fmt.Errorf("invalid CLINIC_TIMEZONE %q: use an IANA name such as Asia/Kolkata", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid CLINIC_TIMEZONE %q: %v", name, err)
+	}
+	location = loc
+	return nil
+}
+
+// Location is the clinic time zone.
+func Location() *time.Location {
+	return location
+}
+
+// In returns t in the clinic time zone.
+func In(t time.Time) time.Time {
+	return t.In(location)
+}
+
+// Now is the current time in the clinic time zone.
+func Now() time.Time {
+	return time.Now().In(location)
+}
+
+// Midnight is the start of date's day in the clinic time zone. It uses
+// date's own year, month and day, so pass a calendar date, such as a date
+// column or a parsed YYYY-MM-DD; for an instant use Midnight(In(t)).
+func Midnight(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+}
+
+// Today is the start of the current day in the clinic.
+func Today() time.Time {
+	return Midnight(Now())
+}
+
+// At is the instant the clinic's clocks show clock on date's day.
+func At(date, clock time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, location)
+}
+
+// FormatDate formats t's date in the clinic for display.
+func FormatDate(t time.Time) string {
+	return In(t).Format(DateLayout)
+}
+
+// FormatClock formats t's time of day in the clinic for display.
+func FormatClock(t time.Time) string {
+	return In(t).Format(ClockLayout)
+}
@@ -0,0 +1,40 @@
+package clinic
+
+import "testing"
+
+func TestLoad(t *testing.T) {
+	saved := location
+	t.Cleanup(func() { location = saved })
+
+	tests := []struct {
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{env: "", want: "UTC"},
+		{env: "Asia/Kolkata", want: "Asia/Kolkata"},
+		{env: " Europe/London ", want: "Europe/London"},
+		{env: "Local", wantErr: true},
+		{env: "local", wantErr: true},
+		{env: "Mars/Olympus_Mons", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("CLINIC_TIMEZONE", tt.env)
+			location = nil
+			err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Load() accepted %q", tt.env)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load(): %v", err)
+			}
+			if got := Location().String(); got != tt.want {
+				t.Errorf("Location() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
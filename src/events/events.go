@@ -0,0 +1,67 @@
+// Package events is a small in-process publish/subscribe bus. Domain code
+// publishes what happened; other subsystems (alerts, notifications) subscribe
+// without the publisher knowing about them.
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Event is something that happened in the domain. Payload is specific to the
+// event type and documented next to the type constant that names it.
+type Event struct {
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Payload    interface{} `json:"payload"`
+}
+
+// Handler receives published events. Handlers run on their own goroutine and
+// must not assume the publishing request is still in flight.
+type Handler func(ctx context.Context, e Event)
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string][]Handler{}
+)
+
+// Subscribe registers h for events of the given type. It is meant to be
+// called during start-up.
+func Subscribe(eventType string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[eventType] = append(handlers[eventType], h)
+}
+
+// Publish delivers e to every subscriber of e.Type asynchronously. The
+// context keeps its values (trace, user) but not its cancellation, so
+// handlers outlive the request that published the event.
+func Publish(ctx context.Context, e Event) {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+	mu.RLock()
+	subs := append([]Handler(nil), handlers[e.Type]...)
+	mu.RUnlock()
+
+	ctx = context.WithoutCancel(ctx)
+	for _, h := range subs {
+		go func(h Handler) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					log.Printf("Event handler for %s panicked: %v", e.Type, rec)
+				}
+			}()
+			h(ctx, e)
+		}(h)
+	}
+}
+
+// PublishAll publishes each of es in order.
+func PublishAll(ctx context.Context, es []Event) {
+	for _, e := range es {
+		Publish(ctx, e)
+	}
+}
@@ -2,71 +2,180 @@ package addDetailsHandlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/PragaL15/med_admin_backend/database"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
 	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/patients"
+	"github.com/PragaL15/med_admin_backend/src/utils"
+	"github.com/PragaL15/med_admin_backend/src/validation"
 	"gorm.io/gorm"
 )
 
-func AddPatient(db *gorm.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var input struct {
-            PID      uint `json:"pid"`
-			Name       string    `json:"name"`
-			Phone      string    `json:"number"`
-			Email      string    `json:"email"`
-			Address    string    `json:"address"`
-			Age        int       `json:"age"`
-			Gender     string    `json:"gender"`
-			Occupation string    `json:"occupation"`
-			Language   string    `json:"lang_spoken"`
-			DOB        string    `json:"dob"`
-			CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
-			UpdatedAt  time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
-		}
+// intakeRequest is the body accepted by PatientIntake. p_id, MRN and age are
+// assigned by the server and are rejected as unknown fields.
+type intakeRequest struct {
+	Name             string                    `json:"name"`
+	Phone            string                    `json:"number"`
+	Email            string                    `json:"email"`
+	Address          string                    `json:"address"`
+	Gender           string                    `json:"gender"`
+	Occupation       string                    `json:"occupation"`
+	Language         string                    `json:"lang_spoken"`
+	DOB              string                    `json:"dob"`
+	Status           string                    `json:"status"`
+	Mode             string                    `json:"mode"`
+	Contacts         []models.PatientContact   `json:"contacts" validate:"dive"`
+	EmergencyContact *models.PatientContact    `json:"emergency_contact" validate:"omitempty"`
+	Insurance        []models.PatientInsurance `json:"insurance" validate:"dive"`
+	Consents         []models.PatientConsent   `json:"consents" validate:"dive"`
+}
+
+// intakeConflicts maps the unique constraints an intake can violate to the
+// conflict reported for each.
+var intakeConflicts = map[string]string{
+	"identifier_issuer_value_idx": "Insurance member ID is already registered to another patient",
+	"idx_patient_id_mrn":          "MRN was taken by a concurrent registration, retry the request",
+	"idx_patient_id_p_id":         "Patient number was taken by a concurrent registration, retry the request",
+}
 
+// PatientIntake registers a patient together with their contacts, emergency
+// contact, insurance and consents in a single transaction.
+func PatientIntake(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		var input intakeRequest
 		decoder := json.NewDecoder(r.Body)
 		decoder.DisallowUnknownFields()
 		if err := decoder.Decode(&input); err != nil {
 			log.Println("Error decoding request body:", err)
-			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid JSON format", err))
 			return
 		}
 
 		parsedDOB, err := time.Parse("2006-01-02", input.DOB)
 		if err != nil {
-			log.Println("Error parsing DOB:", err)
-			http.Error(w, "Invalid date format. Use YYYY-MM-DD.", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "dob", Code: "date", Message: "Invalid date format. Use YYYY-MM-DD."}))
 			return
 		}
 
-		patient := models.Patient{
-      PID:       input.PID,
-			Name:      input.Name,
-			Phone:     input.Phone,
-			Email:     input.Email,
-			Address:   input.Address,
-			Age:       input.Age,
-			Gender:    input.Gender,
-			DOB:       parsedDOB,
-      Occupation: input.Occupation,
-      Language: input.Language,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+		intake := patients.Intake{
+			Patient: models.Patient{
+				Name:       input.Name,
+				Phone:      input.Phone,
+				Email:      input.Email,
+				Address:    input.Address,
+				Gender:     input.Gender,
+				Occupation: input.Occupation,
+				Language:   input.Language,
+				DOB:        parsedDOB,
+				Age:        utils.AgeAt(parsedDOB, time.Now()),
+				Status:     input.Status,
+				Mode:       input.Mode,
+			},
+			Contacts:         input.Contacts,
+			EmergencyContact: input.EmergencyContact,
+			Insurance:        input.Insurance,
+			Consents:         input.Consents,
+		}
+		// Contacts that are themselves patients take any missing details from
+		// the linked record before validation, so that everything wrong with
+		// the request is reported in one response.
+		var linkErrs []apierror.FieldError
+		prepare := func(field string, c *models.PatientContact) error {
+			err := patients.PrepareContact(db, 0, c)
+			if errors.Is(err, patients.ErrLinkNotFound) || errors.Is(err, patients.ErrLinkMerged) {
+				linkErrs = append(linkErrs, apierror.FieldError{Field: field + ".linked_p_id", Code: "linked_patient", Message: err.Error()})
+				return nil
+			}
+			return err
+		}
+		for i := range intake.Contacts {
+			if err := prepare(fmt.Sprintf("contacts[%d]", i), &intake.Contacts[i]); err != nil {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to resolve linked patient", err))
+				return
+			}
+		}
+		if intake.EmergencyContact != nil {
+			if err := prepare("emergency_contact", intake.EmergencyContact); err != nil {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to resolve linked patient", err))
+				return
+			}
+		}
+		if err := validation.Join(
+			validation.Struct(intake.Patient),
+			validation.Struct(input),
+			fieldErrors(linkErrs),
+			duplicateInsurance(intake.Insurance),
+		); err != nil {
+			apierror.Write(w, r, err)
+			return
 		}
 
-		if err := db.Create(&patient).Error; err != nil {
-			log.Println("Database error while inserting patient:", err)
-			http.Error(w, "Error inserting new patient", http.StatusInternalServerError)
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		if err := patients.RegisterIntake(db, &intake, userID); err != nil {
+			if errors.Is(err, patients.ErrConsentStatusOnSave) {
+				apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "consents.status", Code: "consent_status", Message: err.Error()}))
+				return
+			}
+			if detail, ok := intakeConflicts[database.ViolatedConstraint(err)]; ok {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeConflict, detail, err))
+				return
+			}
+			if database.IsUniqueViolation(err) {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeConflict, "Patient conflicts with an existing record", err))
+				return
+			}
+			log.Println("Database error while registering patient:", err)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Error registering patient", err))
 			return
 		}
 
+		// Suspected duplicates go to the review queue; creation is not blocked.
+		duplicates, err := patients.FlagDuplicates(db, intake.Patient)
+		if err != nil {
+			log.Println("Error checking for duplicate patients:", err)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Patient created successfully",
-			"patient": patient,
+			"message":             "Patient created successfully",
+			"patient":             intake.Patient,
+			"contacts":            intake.Contacts,
+			"emergency_contact":   intake.EmergencyContact,
+			"insurance":           intake.Insurance,
+			"consents":            intake.Consents,
+			"possible_duplicates": duplicates,
 		})
 	}
 }
+
+func fieldErrors(fields []apierror.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return apierror.Validation(fields...)
+}
+
+// duplicateInsurance reports member IDs repeated for the same payer within
+// the request, which the database would otherwise reject as a conflict with
+// another patient.
+func duplicateInsurance(insurance []models.PatientInsurance) error {
+	var fields []apierror.FieldError
+	seen := map[[2]string]bool{}
+	for i, ins := range insurance {
+		key := [2]string{ins.Payer, ins.MemberID}
+		if ins.MemberID != "" && seen[key] {
+			fields = append(fields, apierror.FieldError{Field: fmt.Sprintf("insurance[%d].member_id", i), Code: "unique", Message: "is listed twice for this payer"})
+		}
+		seen[key] = true
+	}
+	return fieldErrors(fields)
+}
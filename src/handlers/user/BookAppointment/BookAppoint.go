@@ -4,14 +4,17 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"time"
 
 	"gorm.io/gorm"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"github.com/PragaL15/med_admin_backend/src/validation"
 )
 
 func CreateAppointment(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 	
 		if r.Method == http.MethodOptions {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -22,49 +25,44 @@ func CreateAppointment(db *gorm.DB) http.HandlerFunc {
 		}
 
 		if r.Method != http.MethodPost {
-			http.Error(w, `{"status": false, "message": "Method not allowed"}`, http.StatusMethodNotAllowed)
+			apierror.Write(w, r, apierror.New(apierror.CodeMethodNotAllowed, "Method not allowed"))
 			return
 		}
 
 		var appointment models.AppointmentPost
 		if err := json.NewDecoder(r.Body).Decode(&appointment); err != nil {
 			log.Println("Error decoding request body:", err)
-			http.Error(w, `{"status": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
 			return
 		}
 
-	
-		if appointment.PID == 0 || appointment.DID == 0 || appointment.AppDate == "" || appointment.Time == "" {
-			http.Error(w, `{"status": false, "message": "Missing required fields"}`, http.StatusBadRequest)
+		if err := validation.Struct(appointment); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
-	
-		parsedDate, err := time.Parse("02-01-2006", appointment.AppDate)
+		start, err := parseStart(appointment.StartsAt, appointment.AppDate, appointment.Time)
 		if err != nil {
-			log.Println("Error parsing app_date:", err)
-			http.Error(w, `{"status": false, "message": "Invalid date format. Expected DD-MM-YYYY"}`, http.StatusBadRequest)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		parsedTime, err := time.Parse("15:04:05", appointment.Time)
-		if err != nil {
-			log.Println("Error parsing time:", err)
-			http.Error(w, `{"status": false, "message": "Invalid time format. Expected HH:mm:ss"}`, http.StatusBadRequest)
+		created := models.Appointment{
+			PID:             appointment.PID,
+			DID:             appointment.DID,
+			PHealth:         appointment.PHealth,
+			ProblemHint:     appointment.ProblemHint,
+			AppoStatus:      appointment.AppoStatus,
+			DurationMinutes: appointment.DurationMinutes,
+		}
+		appointments.SetStart(&created, start)
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
 			return
 		}
-
-		combinedDateTime := time.Date(
-			parsedDate.Year(), parsedDate.Month(), parsedDate.Day(),
-			parsedTime.Hour(), parsedTime.Minute(), parsedTime.Second(), 0, time.UTC,
-		)
-
-		appointment.AppDate = combinedDateTime.Format("2006-01-02") 
-		appointment.Time = combinedDateTime.Format("15:04:05")      
-
-		if err := db.Create(&appointment).Error; err != nil {
+		if err := appointments.Create(db, &created, actor); err != nil {
 			log.Printf("Error creating appointment: %v", err)
-			http.Error(w, `{"status": false, "message": "Failed to create appointment"}`, http.StatusInternalServerError)
+			writeAppointmentError(w, r, err, "Failed to create appointment")
 			return
 		}
 
@@ -73,11 +71,10 @@ func CreateAppointment(db *gorm.DB) http.HandlerFunc {
 		response := map[string]interface{}{
 			"status":  true,
 			"message": "Appointment created successfully",
-			"data":    appointment,
+			"data":    created,
 		}
 		if err := json.NewEncoder(w).Encode(response); err != nil {
 			log.Printf("Error encoding JSON response: %v", err)
-			http.Error(w, `{"status": false, "message": "Error sending response"}`, http.StatusInternalServerError)
 		}
 	}
 }
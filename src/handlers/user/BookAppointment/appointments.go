@@ -0,0 +1,461 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	"github.com/PragaL15/med_admin_backend/src/events"
+	recordHandlers "github.com/PragaL15/med_admin_backend/src/handlers/user/record"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"github.com/PragaL15/med_admin_backend/src/services/queue"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// starts_at_from and starts_at_to filter on the start instant, so RFC 3339
+// bounds narrow within a day as well; bare dates are clinic days.
+// app_date_from and app_date_to are the older names for them.
+var appointmentListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"p_id":        {Column: "appointments.p_id", Kind: query.Equals, Type: query.Int},
+		"d_id":        {Column: "appointments.d_id", Kind: query.Equals, Type: query.Int},
+		"appo_status": {Column: "appointments.appo_status", Kind: query.Equals},
+		"starts_at":   {Column: appointments.StartSQL, Kind: query.DateRange},
+		"app_date":    {Column: appointments.StartSQL, Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":        {Column: "appointments.id", Field: "ID"},
+		"starts_at": {Column: appointments.StartSQL, Field: "StartsAt"},
+	},
+	DefaultSort: "starts_at",
+	Key:         "id",
+}
+
+// writeAppointmentError maps appointments service errors to API errors.
+func writeAppointmentError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	var conflict *appointments.ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		apiErr := apierror.New(apierror.CodeConflict, "The slot is already booked")
+		for _, c := range conflict.Conflicts {
+			for _, with := range c.With {
+				field := "d_id"
+				if with == appointments.RolePatient {
+					field = "p_id"
+				}
+				apiErr.Fields = append(apiErr.Fields, apierror.FieldError{
+					Field:   field,
+					Code:    "double_booked",
+					Message: fmt.Sprintf("%s already has appointment %d on %s from %s to %s", with, c.AppointmentID, clinic.FormatDate(c.StartsAt), clinic.FormatClock(c.StartsAt), clinic.FormatClock(c.EndsAt)),
+				})
+			}
+		}
+		apierror.Write(w, r, apiErr)
+	case errors.Is(err, appointments.ErrNotFound):
+		apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Appointment not found"))
+	case errors.Is(err, appointments.ErrPatientNotFound), errors.Is(err, appointments.ErrPatientMerged):
+		apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "p_id", Code: "patient", Message: err.Error()}))
+	case errors.Is(err, appointments.ErrDoctorNotFound):
+		apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "d_id", Code: "doctor", Message: err.Error()}))
+	case errors.Is(err, appointments.ErrInitialStatus):
+		apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "appo_status", Code: "appointment_status", Message: err.Error()}))
+	case errors.Is(err, appointments.ErrInPast):
+		apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "starts_at", Code: "future", Message: err.Error()}))
+	case errors.Is(err, appointments.ErrNotPermitted):
+		apierror.Write(w, r, apierror.New(apierror.CodeForbidden, err.Error()))
+	case errors.Is(err, appointments.ErrNotChangeable), errors.Is(err, appointments.ErrUnchanged),
+		errors.Is(err, appointments.ErrIllegalTransition), errors.Is(err, appointments.ErrNotStarted),
+		errors.Is(err, appointments.ErrNotDeletable):
+		apierror.Write(w, r, apierror.New(apierror.CodeConflict, err.Error()))
+	default:
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, detail, err))
+	}
+}
+
+func appointmentIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid appointment ID"))
+		return 0, false
+	}
+	return id, true
+}
+
+// actorFromRequest loads the signed-in user as an appointments.Actor.
+func actorFromRequest(db *gorm.DB, w http.ResponseWriter, r *http.Request) (appointments.Actor, bool) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	actor, err := appointments.LoadActor(db, userID)
+	if err != nil {
+		if errors.Is(err, appointments.ErrUserNotFound) {
+			apierror.Write(w, r, apierror.New(apierror.CodeUnauthorized, "Signed-in user no longer exists"))
+		} else {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to load user", err))
+		}
+		return appointments.Actor{}, false
+	}
+	return actor, true
+}
+
+// parseStart reads the RFC 3339 starts_at or, from older clients, the
+// DD-MM-YYYY date and HH:mm:ss time of the booking form, which are read as
+// the clinic's local time.
+func parseStart(startsAt, appDate, clock string) (time.Time, error) {
+	if startsAt != "" {
+		t, err := time.Parse(time.RFC3339, startsAt)
+		if err != nil {
+			return time.Time{}, apierror.Validation(apierror.FieldError{Field: "starts_at", Code: "datetime", Message: "Invalid date-time format. Expected RFC 3339, such as 2024-05-01T09:30:00+05:30"})
+		}
+		return t, nil
+	}
+	date, err := time.Parse("02-01-2006", appDate)
+	if err != nil {
+		return time.Time{}, apierror.Validation(apierror.FieldError{Field: "app_date", Code: "date", Message: "Invalid date format. Expected DD-MM-YYYY"})
+	}
+	t, err := time.Parse("15:04:05", clock)
+	if err != nil {
+		return time.Time{}, apierror.Validation(apierror.FieldError{Field: "time", Code: "time", Message: "Invalid time format. Expected HH:mm:ss"})
+	}
+	return clinic.At(date, t), nil
+}
+
+// listAppointments serves a paged appointment list narrowed by scope.
+func listAppointments(db *gorm.DB, w http.ResponseWriter, r *http.Request, scope func(*gorm.DB) *gorm.DB) {
+	params, err := query.Parse(r, appointmentListSpec)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	base := params.Filter(scope(appointments.List(db))).Session(&gorm.Session{})
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count appointments", err))
+		return
+	}
+
+	var rows []appointments.Row
+	if err := params.Page(base.Select(appointments.RowColumns)).Find(&rows).Error; err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch appointments", err))
+		return
+	}
+	for i := range rows {
+		rows[i].Fill()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(query.Page{Data: rows, Meta: params.Finish(&rows, total)})
+}
+
+// GetAppointments lists appointments, soonest first. Filter with ?p_id=,
+// ?d_id=, ?appo_status= and ?app_date_from= / ?app_date_to=.
+func GetAppointments(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		listAppointments(db, w, r, func(q *gorm.DB) *gorm.DB { return q })
+	}
+}
+
+// GetPatientAppointments lists the {p_id} patient's appointments.
+func GetPatientAppointments(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		pid, err := strconv.Atoi(mux.Vars(r)["p_id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid patient ID"))
+			return
+		}
+		var count int64
+		if err := db.Model(&models.Patient{}).Where("p_id = ?", pid).Count(&count).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch patient", err))
+			return
+		}
+		if count == 0 {
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Patient not found"))
+			return
+		}
+		listAppointments(db, w, r, func(q *gorm.DB) *gorm.DB {
+			return q.Where("appointments.p_id = ?", pid)
+		})
+	}
+}
+
+// GetDoctorAppointments lists the appointments of the doctor whose row is
+// {id}.
+func GetDoctorAppointments(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doctor, ok := recordHandlers.DoctorFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		listAppointments(db, w, r, func(q *gorm.DB) *gorm.DB {
+			return q.Where("appointments.d_id = ?", doctor.DID)
+		})
+	}
+}
+
+func GetAppointment(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := appointmentIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		row, err := appointments.Get(db, id)
+		if err != nil {
+			writeAppointmentError(w, r, err, "Failed to fetch appointment")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(row)
+	}
+}
+
+// UpdateAppointment edits the notes of an open appointment. Use reschedule
+// to move it and cancel to call it off.
+func UpdateAppointment(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := appointmentIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			PHealth     string `json:"p_health" validate:"max=255"`
+			ProblemHint string `json:"problem_hint" validate:"max=255"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		details := appointments.Details{PHealth: input.PHealth, ProblemHint: input.ProblemHint}
+		if _, err := appointments.Update(db, id, details, actor); err != nil {
+			writeAppointmentError(w, r, err, "Failed to update appointment")
+			return
+		}
+		respondAppointment(db, w, r, id, http.StatusOK)
+	}
+}
+
+// RescheduleAppointment moves an appointment to a new start, given as
+// starts_at or as app_date and time, and optionally to another doctor.
+func RescheduleAppointment(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := appointmentIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			StartsAt string `json:"starts_at"`
+			AppDate  string `json:"app_date" validate:"required_without=StartsAt"`
+			Time     string `json:"time" validate:"required_without=StartsAt"`
+			DID      int    `json:"d_id" validate:"omitempty,gt=0"`
+			Reason   string `json:"reason" validate:"max=500"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		start, err := parseStart(input.StartsAt, input.AppDate, input.Time)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		if _, err := appointments.Reschedule(db, id, start, input.DID, input.Reason, actor); err != nil {
+			writeAppointmentError(w, r, err, "Failed to reschedule appointment")
+			return
+		}
+		respondAppointment(db, w, r, id, http.StatusOK)
+	}
+}
+
+func CancelAppointment(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := appointmentIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Reason string `json:"reason" validate:"required,max=500"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		_, caused, err := appointments.Cancel(db, id, input.Reason, actor)
+		if err != nil {
+			writeAppointmentError(w, r, err, "Failed to cancel appointment")
+			return
+		}
+		events.PublishAll(r.Context(), caused)
+		respondAppointment(db, w, r, id, http.StatusOK)
+	}
+}
+
+// DeleteAppointment removes an appointment booked in error. Admins only;
+// appointments that took place are kept and should be cancelled instead.
+func DeleteAppointment(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := appointmentIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Reason string `json:"reason" validate:"max=500"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		if err := appointments.Delete(db, id, input.Reason, actor); err != nil {
+			writeAppointmentError(w, r, err, "Failed to delete appointment")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UpdateAppointmentStatus moves an appointment along its lifecycle. The
+// allowed next statuses are listed in next_statuses on the appointment.
+func UpdateAppointmentStatus(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := appointmentIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Status string `json:"status" validate:"required,appointment_status"`
+			Reason string `json:"reason" validate:"max=500"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		if strings.EqualFold(input.Status, models.StatusCancelled) && strings.TrimSpace(input.Reason) == "" {
+			apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "reason", Code: "required", Message: "is required to cancel"}))
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		// Checking in and calling in go through the queue, which hands out
+		// tokens and keeps one consultation per doctor.
+		var err error
+		var caused []events.Event
+		switch strings.ToLower(input.Status) {
+		case models.StatusCheckedIn:
+			err = queue.CheckIn(db, id, 0, actor)
+		case models.StatusInConsultation:
+			err = queue.Call(db, id, actor)
+		default:
+			_, caused, err = appointments.Transition(db, id, input.Status, input.Reason, actor)
+		}
+		if err != nil {
+			writeQueueError(w, r, err, "Failed to change appointment status")
+			return
+		}
+		events.PublishAll(r.Context(), caused)
+		respondAppointment(db, w, r, id, http.StatusOK)
+	}
+}
+
+// GetAppointmentHistory returns every change made to an appointment, oldest
+// first.
+func GetAppointmentHistory(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := appointmentIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		if _, err := appointments.Get(db, id); err != nil {
+			writeAppointmentError(w, r, err, "Failed to fetch appointment")
+			return
+		}
+		changes, err := appointments.History(db, id)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch appointment history", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"appointment_id": id,
+			"history":        changes,
+		})
+	}
+}
+
+// respondAppointment writes the appointment as it now stands.
+func respondAppointment(db *gorm.DB, w http.ResponseWriter, r *http.Request, id int, status int) {
+	row, err := appointments.Get(db, id)
+	if err != nil {
+		writeAppointmentError(w, r, err, "Failed to fetch appointment")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(row)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/services/calendar"
+	"gorm.io/gorm"
+)
+
+// GetAppointmentICS downloads the appointment as an .ics file. Downloading
+// it again after a reschedule or cancellation updates the event already
+// imported, since the UID stays the same and the SEQUENCE goes up.
+func GetAppointmentICS(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := appointmentIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		ics, err := calendar.AppointmentICS(db, id)
+		if err != nil {
+			if errors.Is(err, calendar.ErrNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Appointment not found"))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to build calendar file", err))
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="appointment-%d.ics"`, id))
+		w.Write(ics)
+	}
+}
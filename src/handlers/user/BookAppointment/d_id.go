@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"gorm.io/gorm"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 	models "github.com/PragaL15/med_admin_backend/src/model"
 )
 
@@ -15,17 +16,18 @@ type DoctorPatientData struct {
 }
 func GetDoctorsAndPatients(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		var doctors []models.Doctor
 		var patients []models.Patient
 
 		if err := db.Table("doctor_id").Select("d_id", "d_name").Find(&doctors).Error; err != nil {
 			log.Printf("Error retrieving doctors: %v", err)
-			http.Error(w, "Failed to retrieve doctors", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to retrieve doctors", err))
 			return
 		}
 		if err := db.Table("patient_id").Select("p_id", "p_name").Find(&patients).Error; err != nil {
 			log.Printf("Error retrieving patients: %v", err)
-			http.Error(w, "Failed to retrieve patients", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to retrieve patients", err))
 			return
 		}
 		response := DoctorPatientData{
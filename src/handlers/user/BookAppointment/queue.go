@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	recordHandlers "github.com/PragaL15/med_admin_backend/src/handlers/user/record"
+	"github.com/PragaL15/med_admin_backend/src/services/queue"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"gorm.io/gorm"
+)
+
+// writeQueueError maps queue service errors to API errors and falls back to
+// writeAppointmentError for the appointment ones.
+func writeQueueError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	switch {
+	case errors.Is(err, queue.ErrEmpty):
+		apierror.Write(w, r, apierror.New(apierror.CodeNotFound, err.Error()))
+	case errors.Is(err, queue.ErrNotToday), errors.Is(err, queue.ErrAlreadyQueued), errors.Is(err, queue.ErrBusy):
+		apierror.Write(w, r, apierror.New(apierror.CodeConflict, err.Error()))
+	case errors.Is(err, queue.ErrNotPermitted):
+		apierror.Write(w, r, apierror.New(apierror.CodeForbidden, err.Error()))
+	default:
+		writeAppointmentError(w, r, err, detail)
+	}
+}
+
+// CheckInAppointment checks a patient in for today's confirmed appointment
+// and gives them the doctor's next queue token. The optional priority
+// (1 is the most urgent, default 3) moves them up the queue.
+func CheckInAppointment(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := appointmentIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Priority int `json:"priority" validate:"omitempty,gte=1,lte=5"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		if err := queue.CheckIn(db, id, input.Priority, actor); err != nil {
+			writeQueueError(w, r, err, "Failed to check in")
+			return
+		}
+		respondAppointment(db, w, r, id, http.StatusOK)
+	}
+}
+
+// AddWalkIn books a patient without an appointment into a doctor's queue
+// for now, checked in and with a token.
+func AddWalkIn(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+
+		var input struct {
+			PID             int    `json:"p_id" validate:"required,gt=0"`
+			DID             int    `json:"d_id" validate:"required,gt=0"`
+			Priority        int    `json:"priority" validate:"omitempty,gte=1,lte=5"`
+			PHealth         string `json:"p_health" validate:"max=255"`
+			ProblemHint     string `json:"problem_hint" validate:"max=255"`
+			DurationMinutes int    `json:"duration_minutes" validate:"omitempty,gte=5,lte=480"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		a, err := queue.AddWalkIn(db, queue.WalkIn{
+			PID:             input.PID,
+			DID:             input.DID,
+			Priority:        input.Priority,
+			PHealth:         input.PHealth,
+			ProblemHint:     input.ProblemHint,
+			DurationMinutes: input.DurationMinutes,
+		}, actor)
+		if err != nil {
+			writeQueueError(w, r, err, "Failed to add walk-in")
+			return
+		}
+		respondAppointment(db, w, r, a.ID, http.StatusCreated)
+	}
+}
+
+// GetDoctorQueue returns the doctor's queue for today: who is in
+// consultation and who is waiting, in order, with estimated waits.
+func GetDoctorQueue(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doctor, ok := recordHandlers.DoctorFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		q, err := queue.Doctor(db, int(doctor.DID))
+		if err != nil {
+			writeQueueError(w, r, err, "Failed to fetch queue")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(q)
+	}
+}
+
+// CallNextPatient calls the first waiting patient in to the doctor. The
+// doctor's current consultation must be completed first.
+func CallNextPatient(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doctor, ok := recordHandlers.DoctorFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		id, err := queue.CallNext(db, int(doctor.DID), actor)
+		if err != nil {
+			writeQueueError(w, r, err, "Failed to call next patient")
+			return
+		}
+		respondAppointment(db, w, r, id, http.StatusOK)
+	}
+}
+
+// GetQueueDisplay is the read-only board for waiting-room screens: token
+// numbers being served and waiting, per doctor, without patient details.
+// Limit it to some doctors, at most queue.MaxBoards, with ?d_id=1,2.
+func GetQueueDisplay(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		var dids []int
+		if v := r.URL.Query().Get("d_id"); v != "" {
+			for _, part := range strings.Split(v, ",") {
+				did, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid d_id"))
+					return
+				}
+				dids = append(dids, did)
+			}
+			if len(dids) > queue.MaxBoards {
+				apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, fmt.Sprintf("d_id lists at most %d doctors", queue.MaxBoards)))
+				return
+			}
+		}
+		boards, err := queue.Boards(db, dids)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch queues", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": boards})
+	}
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	"github.com/PragaL15/med_admin_backend/src/services/recurring"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// writeSeriesError maps recurring service errors to API errors and falls
+// back to writeAppointmentError for the per-occurrence ones.
+func writeSeriesError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	var occurrences *recurring.OccurrenceError
+	switch {
+	case errors.As(err, &occurrences):
+		apiErr := apierror.New(apierror.CodeConflict, err.Error())
+		for _, s := range occurrences.Skipped {
+			apiErr.Fields = append(apiErr.Fields, apierror.FieldError{
+				Field:   "occurrences",
+				Code:    s.Reason,
+				Message: clinic.FormatDate(s.StartsAt) + " " + clinic.FormatClock(s.StartsAt),
+			})
+		}
+		apierror.Write(w, r, apiErr)
+	case errors.Is(err, recurring.ErrInvalidRule), errors.Is(err, recurring.ErrUnbounded), errors.Is(err, recurring.ErrTooManyOccurrences):
+		apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "rrule", Code: "rrule", Message: err.Error()}))
+	case errors.Is(err, recurring.ErrNotFound):
+		apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Series not found"))
+	case errors.Is(err, recurring.ErrNotInSeries):
+		apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "from_appointment_id", Code: "series", Message: err.Error()}))
+	case errors.Is(err, recurring.ErrNothingBooked), errors.Is(err, recurring.ErrNothingToEdit):
+		apierror.Write(w, r, apierror.New(apierror.CodeConflict, err.Error()))
+	default:
+		writeAppointmentError(w, r, err, detail)
+	}
+}
+
+func seriesIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid series ID"))
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateAppointmentSeries books a recurring series. starts_at, or app_date
+// and time, is the first occurrence; rrule gives the pattern, e.g.
+// "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=12". By default nothing is booked when
+// any occurrence is outside the doctor's schedule or double-booked; with
+// skip_unavailable those occurrences are reported and the rest are booked.
+func CreateAppointmentSeries(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+
+		var input struct {
+			PID             int    `json:"p_id" validate:"required,gt=0"`
+			DID             int    `json:"d_id" validate:"required,gt=0"`
+			StartsAt        string `json:"starts_at"`
+			AppDate         string `json:"app_date" validate:"required_without=StartsAt"`
+			Time            string `json:"time" validate:"required_without=StartsAt"`
+			DurationMinutes int    `json:"duration_minutes" validate:"omitempty,gte=5,lte=480"`
+			RRule           string `json:"rrule" validate:"required,max=255"`
+			PHealth         string `json:"p_health" validate:"max=255"`
+			ProblemHint     string `json:"problem_hint" validate:"max=255"`
+			SkipUnavailable bool   `json:"skip_unavailable"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		start, err := parseStart(input.StartsAt, input.AppDate, input.Time)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		result, err := recurring.Create(db, recurring.Booking{
+			PID:             input.PID,
+			DID:             input.DID,
+			Start:           start,
+			DurationMinutes: input.DurationMinutes,
+			RRule:           input.RRule,
+			PHealth:         input.PHealth,
+			ProblemHint:     input.ProblemHint,
+			SkipUnavailable: input.SkipUnavailable,
+		}, actor)
+		if err != nil {
+			writeSeriesError(w, r, err, "Failed to book series")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func GetAppointmentSeries(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := seriesIDFromPath(w, r)
+		if !ok {
+			return
+		}
+		series, occurrences, err := recurring.Get(db, id)
+		if err != nil {
+			writeSeriesError(w, r, err, "Failed to fetch series")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"series":      series,
+			"occurrences": occurrences,
+		})
+	}
+}
+
+// EditAppointmentSeries changes from_appointment_id and every open
+// occurrence after it. To change one occurrence only, use the appointment
+// endpoints on it.
+func EditAppointmentSeries(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := seriesIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			FromAppointmentID int     `json:"from_appointment_id" validate:"required,gt=0"`
+			Time              string  `json:"time" validate:"omitempty,datetime=15:04:05"`
+			DID               int     `json:"d_id" validate:"omitempty,gt=0"`
+			PHealth           *string `json:"p_health" validate:"omitempty,max=255"`
+			ProblemHint       *string `json:"problem_hint" validate:"omitempty,max=255"`
+			Reason            string  `json:"reason" validate:"max=500"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		edited, err := recurring.EditRemainder(db, id, input.FromAppointmentID, recurring.Edit{
+			Clock:       input.Time,
+			DID:         input.DID,
+			PHealth:     input.PHealth,
+			ProblemHint: input.ProblemHint,
+			Reason:      input.Reason,
+		}, actor)
+		if err != nil {
+			writeSeriesError(w, r, err, "Failed to edit series")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"series_id":   id,
+			"occurrences": edited,
+		})
+	}
+}
+
+// CancelAppointmentSeries cancels from_appointment_id and every open
+// occurrence after it.
+func CancelAppointmentSeries(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := seriesIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			FromAppointmentID int    `json:"from_appointment_id" validate:"required,gt=0"`
+			Reason            string `json:"reason" validate:"required,max=500"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		cancelled, err := recurring.CancelRemainder(db, id, input.FromAppointmentID, input.Reason, actor)
+		if err != nil {
+			writeSeriesError(w, r, err, "Failed to cancel series")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"series_id":   id,
+			"occurrences": cancelled,
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/services/waitlist"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// The default order is the order slots are offered in.
+var waitlistListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"p_id":     {Column: "p_id", Kind: query.Equals, Type: query.Int},
+		"d_id":     {Column: "d_id", Kind: query.Equals, Type: query.Int},
+		"status":   {Column: "status", Kind: query.Equals},
+		"priority": {Column: "priority", Kind: query.Equals, Type: query.Int},
+	},
+	Sorts: map[string]query.Sort{
+		"id":         {Column: "id", Field: "ID"},
+		"priority":   {Column: "priority", Field: "Priority"},
+		"created_at": {Column: "created_at", Field: "CreatedAt"},
+	},
+	DefaultSort: "priority",
+	Key:         "id",
+}
+
+var waitlistOfferListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"p_id":      {Column: "p_id", Kind: query.Equals, Type: query.Int},
+		"d_id":      {Column: "d_id", Kind: query.Equals, Type: query.Int},
+		"status":    {Column: "status", Kind: query.Equals},
+		"starts_at": {Column: "starts_at", Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":        {Column: "id", Field: "ID"},
+		"starts_at": {Column: "starts_at", Field: "StartsAt"},
+	},
+	DefaultSort: "-id",
+	Key:         "id",
+}
+
+// writeWaitlistError maps waitlist service errors to API errors and falls
+// back to writeAppointmentError for the booking ones.
+func writeWaitlistError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	switch {
+	case errors.Is(err, waitlist.ErrNotFound):
+		apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Waitlist entry not found"))
+	case errors.Is(err, waitlist.ErrOfferNotFound):
+		apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Offer not found"))
+	case errors.Is(err, waitlist.ErrOfferClosed), errors.Is(err, waitlist.ErrOfferExpired):
+		apierror.Write(w, r, apierror.New(apierror.CodeConflict, err.Error()))
+	case errors.Is(err, waitlist.ErrNotPermitted):
+		apierror.Write(w, r, apierror.New(apierror.CodeForbidden, err.Error()))
+	default:
+		writeAppointmentError(w, r, err, detail)
+	}
+}
+
+func waitlistIDFromPath(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid "+name+" ID"))
+		return 0, false
+	}
+	return id, true
+}
+
+// listWaitlist serves a paged list into rows, a pointer to a slice of the
+// model to list.
+func listWaitlist(db *gorm.DB, w http.ResponseWriter, r *http.Request, spec query.Spec, rows interface{}, name string) {
+	params, err := query.Parse(r, spec)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	base := params.Filter(db.Model(rows)).Session(&gorm.Session{})
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count "+name, err))
+		return
+	}
+	if err := params.Page(base).Find(rows).Error; err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch "+name, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(query.Page{Data: rows, Meta: params.Finish(rows, total)})
+}
+
+func GetWaitlist(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows := []models.WaitlistEntry{}
+		listWaitlist(db.WithContext(r.Context()), w, r, waitlistListSpec, &rows, "waitlist")
+	}
+}
+
+func GetWaitlistOffers(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows := []models.WaitlistOffer{}
+		listWaitlist(db.WithContext(r.Context()), w, r, waitlistOfferListSpec, &rows, "offers")
+	}
+}
+
+// AddToWaitlist puts a patient on a doctor's waitlist for any slot between
+// earliest_date and latest_date. When an appointment in that range is
+// cancelled, the slot is offered to waiting patients by priority (1 is the
+// most urgent), then by how long they have waited.
+func AddToWaitlist(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+
+		var entry models.WaitlistEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(entry); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		if err := waitlist.Add(db, &entry, userID); err != nil {
+			writeWaitlistError(w, r, err, "Failed to add to waitlist")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(entry)
+	}
+}
+
+func RemoveFromWaitlist(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := waitlistIDFromPath(w, r, "waitlist entry")
+		if !ok {
+			return
+		}
+		if err := waitlist.Remove(db, id); err != nil {
+			writeWaitlistError(w, r, err, "Failed to remove from waitlist")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AcceptWaitlistOffer books the held slot for the patient it was offered to.
+func AcceptWaitlistOffer(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := waitlistIDFromPath(w, r, "offer")
+		if !ok {
+			return
+		}
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		offer, appointment, err := waitlist.Accept(db, id, actor)
+		if err != nil {
+			writeWaitlistError(w, r, err, "Failed to accept offer")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"offer":       offer,
+			"appointment": appointment,
+		})
+	}
+}
+
+// DeclineWaitlistOffer returns the patient to the waitlist and offers the
+// slot to the next patient in line.
+func DeclineWaitlistOffer(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, ok := waitlistIDFromPath(w, r, "offer")
+		if !ok {
+			return
+		}
+		actor, ok := actorFromRequest(db, w, r)
+		if !ok {
+			return
+		}
+		offer, err := waitlist.Decline(db, id, actor)
+		if err != nil {
+			writeWaitlistError(w, r, err, "Failed to decline offer")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(offer)
+	}
+}
@@ -1,14 +1,33 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
 	"gorm.io/gorm"
 )
+var admittedListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"ward_no":          {Column: "admitted.ward_no", Kind: query.Equals},
+		"p_id":             {Column: "admitted.p_id", Kind: query.Equals, Type: query.Int},
+		"p_operation_date": {Column: "admitted.p_operation_date", Kind: query.DateRange},
+		"created_at":       {Column: "admitted.created_at", Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":               {Column: "admitted.id", Field: "ID"},
+		"p_name":           {Column: "patient_id.p_name", Field: "PName"},
+		"ward_no":          {Column: "admitted.ward_no", Field: "WardNo"},
+		"p_operation_date": {Column: "admitted.p_operation_date", Field: "POperationDate"},
+	},
+	DefaultSort: "ward_no",
+	Key:         "id",
+}
+
 func GetAdmittedPatients(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
@@ -19,22 +38,35 @@ func GetAdmittedPatients(db *gorm.DB) http.HandlerFunc {
 		}
 
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, r, apierror.New(apierror.CodeMethodNotAllowed, "Method not allowed"))
+			return
+		}
+
+		params, err := query.Parse(r, admittedListSpec)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		base := params.Filter(db.Table("admitted").
+			Joins("JOIN patient_id ON admitted.p_id = patient_id.p_id")).
+			Session(&gorm.Session{})
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Error counting admitted patients", err))
 			return
 		}
 
 		var admittedRecords []models.Admitted
-		err := db.Table("admitted").
+		err = params.Page(base).
 			Select(`admitted.id, admitted.p_id, patient_id.p_name, admitted.p_health, 
                     admitted.p_operation, admitted.p_operation_date, admitted.p_operated_doctor, 
                     admitted.duration_admit, admitted.ward_no`).
-			Joins("JOIN patient_id ON admitted.p_id = patient_id.p_id").
 			Find(&admittedRecords).Error
 		if err != nil {
-			http.Error(w, "Error fetching admitted patient data: "+err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Error fetching admitted patient data", err))
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(admittedRecords)
+		query.WriteList(w, r, admittedRecords, params.Finish(&admittedRecords, total))
 	}
 }
@@ -1,14 +1,46 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
-	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"github.com/PragaL15/med_admin_backend/src/services/patients"
 	"gorm.io/gorm"
 )
 
+// app_date is kept as a filter and sort key, and time as a sort key, for
+// older clients; they work on the start instant like starts_at.
+var appointmentListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"appo_status": {Column: "appointments.appo_status", Kind: query.Equals},
+		"d_id":        {Column: "appointments.d_id", Kind: query.Equals, Type: query.Int},
+		"p_id":        {Column: "appointments.p_id", Kind: query.Equals, Type: query.Int},
+		"starts_at":   {Column: appointments.StartSQL, Kind: query.DateRange},
+		"app_date":    {Column: appointments.StartSQL, Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":          {Column: "appointments.id", Field: "ID"},
+		"starts_at":   {Column: appointments.StartSQL, Field: "StartsAt"},
+		"app_date":    {Column: appointments.StartSQL, Field: "StartsAt"},
+		"time":        {Column: appointments.StartSQL, Field: "StartsAt"},
+		"p_name":      {Column: "patient_id.p_name", Field: "PName"},
+		"appo_status": {Column: "appointments.appo_status", Field: "AppoStatus"},
+	},
+	DefaultSort: "-starts_at",
+	Key:         "id",
+}
+
+// appointmentRow is an appointment with the patient's active allergies and
+// problems, so they are in front of the clinician before the visit.
+type appointmentRow struct {
+	appointments.Row
+	*patients.Clinical
+}
+
 func GetAppointments(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		if r.Method == http.MethodOptions {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
@@ -18,32 +50,57 @@ func GetAppointments(db *gorm.DB) http.HandlerFunc {
 		}
 
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, r, apierror.New(apierror.CodeMethodNotAllowed, "Method not allowed"))
+			return
+		}
+
+		params, err := query.Parse(r, appointmentListSpec)
+		if err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
-		var appointments []models.Appointment
-		err := db.Table("appointments").
+		base := params.Filter(db.Table("appointments").
+			Joins("JOIN patient_id ON appointments.p_id = patient_id.p_id")).
+			Session(&gorm.Session{})
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Error counting appointments", err))
+			return
+		}
+
+		var list []appointments.Row
+		err = params.Page(base).
     Select(`appointments.id, appointments.p_id, patient_id.p_name, 
-            appointments.app_date, appointments.p_health, 
-            appointments.d_id, appointments.time, 
+            appointments.starts_at, appointments.ends_at, appointments.p_health, 
+            appointments.d_id, appointments.duration_minutes, 
             appointments.problem_hint,patient_id.p_number, appointments.appo_status`).
-    Joins("JOIN patient_id ON appointments.p_id = patient_id.p_id").
-    Find(&appointments).Error
+    Find(&list).Error
 
 
 		if err != nil {
-			http.Error(w, "Error fetching appointments: "+err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Error fetching appointments", err))
 			return
 		}
 
-		for i := range appointments {
-			appointments[i].Time = appointments[i].AppDate.Format("03:04 PM") 
+		meta := params.Finish(&list, total)
+
+		pids := make([]uint, 0, len(list))
+		for i := range list {
+			list[i].Fill()
+			pids = append(pids, uint(list[i].PID))
+		}
+		clinical, err := patients.ActiveClinical(db, pids)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Error fetching allergies and problems", err))
+			return
+		}
+		rows := make([]appointmentRow, len(list))
+		for i, a := range list {
+			rows[i] = appointmentRow{Row: a, Clinical: clinical[uint(a.PID)]}
 		}
 
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(appointments)
+		query.WriteList(w, r, rows, meta)
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 	"gorm.io/gorm"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 )
 type PatientStatusRecord struct {
 	PatientID int       `json:"p_id"`
@@ -13,6 +14,7 @@ type PatientStatusRecord struct {
 }
 func GetPatientStatusForGraph(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
@@ -23,7 +25,7 @@ func GetPatientStatusForGraph(db *gorm.DB) http.HandlerFunc {
 		}
 
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, r, apierror.New(apierror.CodeMethodNotAllowed, "Method not allowed"))
 			return
 		}
 
@@ -36,7 +38,7 @@ func GetPatientStatusForGraph(db *gorm.DB) http.HandlerFunc {
 			Find(&records).Error
 
 		if err != nil {
-			http.Error(w, "Error fetching patient status data: "+err.Error(), http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Error fetching patient status data", err))
 			return
 		}
 
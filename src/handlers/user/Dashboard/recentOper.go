@@ -6,10 +6,12 @@ import (
 	"net/http"
 
 	"gorm.io/gorm"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 	models "github.com/PragaL15/med_admin_backend/src/model"
 )
 func RecentOperation(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
@@ -19,7 +21,7 @@ func RecentOperation(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Write(w, r, apierror.New(apierror.CodeMethodNotAllowed, "Method not allowed"))
 			return
 		}
 		var admittedRecords []models.Admitted
@@ -32,11 +34,11 @@ func RecentOperation(db *gorm.DB) http.HandlerFunc {
 
 		if err != nil {
 			log.Printf("Error executing query: %v", err) 
-			http.Error(w, "Error fetching admitted records", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Error fetching admitted records", err))
 			return
 		}
 		if len(admittedRecords) == 0 {
-			http.Error(w, "No admitted records found", http.StatusNotFound)
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "No admitted records found"))
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -44,7 +46,6 @@ func RecentOperation(db *gorm.DB) http.HandlerFunc {
 		err = json.NewEncoder(w).Encode(admittedRecords)
 		if err != nil {
 			log.Printf("Error encoding JSON response: %v", err)
-			http.Error(w, "Error sending response", http.StatusInternalServerError)
 		}
 	}
 }
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/services/patients"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var duplicateListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"status":     {Column: "status", Kind: query.Equals},
+		"p_id":       {Column: "p_id", Kind: query.Equals, Type: query.Int},
+		"created_at": {Column: "created_at", Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":         {Column: "id", Field: "ID"},
+		"score":      {Column: "score", Field: "Score"},
+		"created_at": {Column: "created_at", Field: "CreatedAt"},
+	},
+	DefaultSort: "-score",
+	Key:         "id",
+}
+
+// GetDuplicateQueue lists suspected duplicate pairs; pass ?status=pending for
+// the open review queue.
+func GetDuplicateQueue(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		params, err := query.Parse(r, duplicateListSpec)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		base := params.Filter(db.Model(&models.DuplicateCandidate{})).Session(&gorm.Session{})
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count duplicate candidates", err))
+			return
+		}
+
+		var candidates []models.DuplicateCandidate
+		if err := params.Page(base).Find(&candidates).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch duplicate candidates", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(query.Page{Data: candidates, Meta: params.Finish(&candidates, total)})
+	}
+}
+
+func DismissDuplicate(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid duplicate candidate ID"))
+			return
+		}
+		userID, _ := middleware.UserIDFromContext(r.Context())
+
+		result := db.Model(&models.DuplicateCandidate{}).
+			Where("id = ? AND status = ?", id, models.DuplicatePending).
+			Updates(map[string]interface{}{
+				"status":      models.DuplicateDismissed,
+				"reviewed_by": userID,
+				"reviewed_at": time.Now(),
+			})
+		if result.Error != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to dismiss duplicate candidate", result.Error))
+			return
+		}
+		if result.RowsAffected == 0 {
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "No pending duplicate candidate with this ID"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Duplicate candidate dismissed"})
+	}
+}
+
+func MergePatients(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		var input struct {
+			SurvivorPID uint `json:"survivor_p_id"`
+			MergedPID   uint `json:"merged_p_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		var fields []apierror.FieldError
+		if input.SurvivorPID == 0 {
+			fields = append(fields, apierror.FieldError{Field: "survivor_p_id", Code: "required", Message: "is required"})
+		}
+		if input.MergedPID == 0 {
+			fields = append(fields, apierror.FieldError{Field: "merged_p_id", Code: "required", Message: "is required"})
+		}
+		if len(fields) > 0 {
+			apierror.Write(w, r, apierror.Validation(fields...))
+			return
+		}
+		userID, _ := middleware.UserIDFromContext(r.Context())
+
+		audit, err := patients.Merge(db, input.SurvivorPID, input.MergedPID, userID)
+		switch {
+		case errors.Is(err, patients.ErrSamePatient):
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, err.Error()))
+			return
+		case errors.Is(err, patients.ErrNotFound):
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "One or both patients do not exist"))
+			return
+		case errors.Is(err, patients.ErrAlreadyMerged):
+			apierror.Write(w, r, apierror.New(apierror.CodeConflict, err.Error()))
+			return
+		case err != nil:
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to merge patients", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Patients merged successfully",
+			"merge":   audit,
+		})
+	}
+}
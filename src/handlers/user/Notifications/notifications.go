@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/services/notifications"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var notificationListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"p_id":           {Column: "p_id", Kind: query.Equals, Type: query.Int},
+		"appointment_id": {Column: "appointment_id", Kind: query.Equals, Type: query.Int},
+		"offer_id":       {Column: "offer_id", Kind: query.Equals, Type: query.Int},
+		"kind":           {Column: "kind", Kind: query.Equals},
+		"channel":        {Column: "channel", Kind: query.Equals},
+		"status":         {Column: "status", Kind: query.Equals},
+		"due_at":         {Column: "due_at", Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":     {Column: "id", Field: "ID"},
+		"due_at": {Column: "due_at", Field: "DueAt"},
+	},
+	DefaultSort: "-id",
+	Key:         "id",
+}
+
+func GetNotifications(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		params, err := query.Parse(r, notificationListSpec)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		base := params.Filter(db.Model(&models.Notification{})).Session(&gorm.Session{})
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count notifications", err))
+			return
+		}
+		rows := []models.Notification{}
+		if err := params.Page(base).Find(&rows).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch notifications", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(query.Page{Data: rows, Meta: params.Finish(&rows, total)})
+	}
+}
+
+func GetNotification(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid notification ID"))
+			return
+		}
+		var n models.Notification
+		if err := db.Where("id = ?", id).Take(&n).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Notification not found"))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch notification", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(n)
+	}
+}
+
+// RecordDeliveryReport takes delivery reports from notification providers.
+// It is outside the authenticated API; providers send the shared
+// NOTIFICATIONS_CALLBACK_TOKEN in X-Callback-Token, and without one set
+// reports are refused.
+func RecordDeliveryReport(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		token := os.Getenv("NOTIFICATIONS_CALLBACK_TOKEN")
+		if token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Callback-Token")), []byte(token)) != 1 {
+			apierror.Write(w, r, apierror.New(apierror.CodeUnauthorized, "Invalid callback token"))
+			return
+		}
+
+		var input struct {
+			Reference  string `json:"reference" validate:"required_without=ProviderID,max=50"`
+			ProviderID string `json:"id" validate:"max=255"`
+			Status     string `json:"status" validate:"required,oneof=delivered failed"`
+			Error      string `json:"error" validate:"max=500"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		n, err := notifications.RecordDelivery(db, notifications.Report{
+			Reference:  input.Reference,
+			ProviderID: input.ProviderID,
+			Status:     input.Status,
+			Error:      input.Error,
+		})
+		if err != nil {
+			if errors.Is(err, notifications.ErrNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Notification not found"))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to record delivery report", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(n)
+	}
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 	"github.com/PragaL15/med_admin_backend/database"
+	"github.com/PragaL15/med_admin_backend/src/metrics"
 	models "github.com/PragaL15/med_admin_backend/src/model"
 	"github.com/PragaL15/med_admin_backend/src/utils"
 	"golang.org/x/crypto/bcrypt"
@@ -25,37 +27,40 @@ type LoginResponse struct {
 func Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"message":"Invalid request payload","status":false}`, http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request payload", err))
 		return
 	}
 	var user models.User
-	err := database.DB.Where("username = ?", req.Username).First(&user).Error
+	err := database.DB.WithContext(r.Context()).Where("username = ?", req.Username).First(&user).Error
 	if err != nil {
-		http.Error(w, `{"message":"Invalid username or password","status":false}`, http.StatusUnauthorized)
+		metrics.LoginFailures.WithLabelValues("unknown_user").Inc()
+		apierror.Write(w, r, apierror.New(apierror.CodeInvalidCredentials, "Invalid username or password"))
 		return
 	}
 
 	if user.Status != 1 {
-		http.Error(w, `{"message":"Account is inactive","status":false}`, http.StatusUnauthorized)
+		metrics.LoginFailures.WithLabelValues("inactive").Inc()
+		apierror.Write(w, r, apierror.New(apierror.CodeAccountInactive, "Account is inactive"))
 		return
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		http.Error(w, `{"message":"Invalid username or password","status":false}`, http.StatusUnauthorized)
+		metrics.LoginFailures.WithLabelValues("bad_password").Inc()
+		apierror.Write(w, r, apierror.New(apierror.CodeInvalidCredentials, "Invalid username or password"))
 		return
 	}
 
 	tokenString, err := utils.GenerateJWT(user.UserID)
 	if err != nil {
-		http.Error(w, `{"message":"Could not generate token","status":false}`, http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Could not generate token", err))
 		return
 	}
 
 	fmt.Printf("Generated Token: %s\n", tokenString)
 	decodedUserID, err := utils.DecodeJWTTokenAndGetUserID(tokenString)
 	if err != nil {
-		http.Error(w, `{"message":"Error decoding token","status":false}`, http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Error decoding token", err))
 		return
 	}
 
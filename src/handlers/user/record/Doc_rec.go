@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -8,14 +9,22 @@ import (
 
 	"github.com/gorilla/mux"
 	 "gorm.io/gorm"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/validation"
 )
 
 func CreateDoctor(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		var doctor models.Doctor
 		if err := json.NewDecoder(r.Body).Decode(&doctor); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(doctor); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
@@ -24,7 +33,7 @@ func CreateDoctor(db *gorm.DB) http.HandlerFunc {
 
 		if err := db.Create(&doctor).Error; err != nil {
 			log.Printf("Error creating doctor: %v", err)
-			http.Error(w, "Failed to create doctor", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to create doctor", err))
 			return
 		}
 
@@ -33,30 +42,59 @@ func CreateDoctor(db *gorm.DB) http.HandlerFunc {
 	}
 }
 
+var doctorListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"d_id":       {Column: "d_id", Kind: query.Equals, Type: query.Int},
+		"status":     {Column: "d_status", Kind: query.Equals},
+		"created_at": {Column: "created_at", Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":         {Column: "id", Field: "ID"},
+		"d_id":       {Column: "d_id", Field: "DID"},
+		"name":       {Column: "d_name", Field: "DName"},
+		"created_at": {Column: "created_at", Field: "CreatedAt"},
+	},
+	DefaultSort: "d_id",
+	Key:         "id",
+}
+
 func GetAllDoctors(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var doctors []models.Doctor
+		db := db.WithContext(r.Context())
+		params, err := query.Parse(r, doctorListSpec)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
 
-		if err := db.Find(&doctors).Error; err != nil {
+		base := params.Filter(db.Model(&models.Doctor{})).Session(&gorm.Session{})
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count doctors", err))
+			return
+		}
+
+		var doctors []models.Doctor
+		if err := params.Page(base).Find(&doctors).Error; err != nil {
 			log.Printf("Error retrieving doctors: %v", err)
-			http.Error(w, "Failed to retrieve doctors", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to retrieve doctors", err))
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(doctors)
+		query.WriteList(w, r, doctors, params.Finish(&doctors, total))
 	}
 }
 func GetDoctorByID(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		id := mux.Vars(r)["id"]
 		var doctor models.Doctor
 		if err := db.Where("id = ?", id).First(&doctor).Error; err != nil {
-			if err.Error() == "record not found" {
-				http.Error(w, "Doctor not found", http.StatusNotFound)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Doctor not found"))
 			} else {
 				log.Printf("Error retrieving doctor: %v", err)
-				http.Error(w, "Failed to retrieve doctor", http.StatusInternalServerError)
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to retrieve doctor", err))
 			}
 			return
 		}
@@ -66,11 +104,16 @@ func GetDoctorByID(db *gorm.DB) http.HandlerFunc {
 }
 func UpdateDoctor(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		id := mux.Vars(r)["id"]
 		var doctor models.Doctor
 
 		if err := json.NewDecoder(r.Body).Decode(&doctor); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(doctor); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 		doctor.UpdatedAt = time.Now()
@@ -83,7 +126,7 @@ func UpdateDoctor(db *gorm.DB) http.HandlerFunc {
 			"updated_at": doctor.UpdatedAt,
 		}).Error; err != nil {
 			log.Printf("Error updating doctor: %v", err)
-			http.Error(w, "Failed to update doctor", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to update doctor", err))
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -92,15 +135,16 @@ func UpdateDoctor(db *gorm.DB) http.HandlerFunc {
 }
 func DeleteDoctor(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		id := mux.Vars(r)["id"]
 		result := db.Where("id = ?", id).Delete(&models.Doctor{})
 		if result.Error != nil {
 			log.Printf("Error deleting doctor: %v", result.Error)
-			http.Error(w, "Failed to delete doctor", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to delete doctor", result.Error))
 			return
 		}
 		if result.RowsAffected == 0 {
-			http.Error(w, "Doctor not found", http.StatusNotFound)
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Doctor not found"))
 			return
 		}
 		w.WriteHeader(http.StatusOK)
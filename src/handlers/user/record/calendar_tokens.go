@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/calendar"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// calendarOwner resolves the feed owner named in the path to its d_id or
+// p_id, writing the error itself when it cannot.
+type calendarOwner func(db *gorm.DB, w http.ResponseWriter, r *http.Request) (int, bool)
+
+func doctorCalendarOwner(db *gorm.DB, w http.ResponseWriter, r *http.Request) (int, bool) {
+	doctor, ok := DoctorFromPath(db, w, r)
+	if !ok {
+		return 0, false
+	}
+	return int(doctor.DID), true
+}
+
+func patientCalendarOwner(db *gorm.DB, w http.ResponseWriter, r *http.Request) (int, bool) {
+	patient, ok := patientFromPath(db, w, r)
+	if !ok {
+		return 0, false
+	}
+	return int(patient.PID), true
+}
+
+// feedURL is the absolute subscription URL for token, as calendar clients
+// need it.
+func feedURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/calendar/" + token + ".ics"
+}
+
+func listCalendarTokens(db *gorm.DB, owner string, resolve calendarOwner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		ownerID, ok := resolve(db, w, r)
+		if !ok {
+			return
+		}
+		tokens, err := calendar.Tokens(db, owner, ownerID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch calendar tokens", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	}
+}
+
+// issueCalendarToken answers with the feed URL, which carries the token.
+// It is only shown here; a lost URL is replaced by issuing a new token and
+// revoking the old one.
+func issueCalendarToken(db *gorm.DB, owner string, resolve calendarOwner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		ownerID, ok := resolve(db, w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Label string `json:"label" validate:"max=100"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+				return
+			}
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		token, t, err := calendar.Issue(db, owner, ownerID, input.Label, userID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to issue calendar token", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":    t,
+			"feed_url": feedURL(r, token),
+		})
+	}
+}
+
+func revokeCalendarToken(db *gorm.DB, owner string, resolve calendarOwner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		ownerID, ok := resolve(db, w, r)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(mux.Vars(r)["token_id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid calendar token ID"))
+			return
+		}
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		t, err := calendar.Revoke(db, owner, ownerID, id, userID)
+		if err != nil {
+			if errors.Is(err, calendar.ErrTokenNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Calendar token not found"))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to revoke calendar token", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	}
+}
+
+func GetDoctorCalendarTokens(db *gorm.DB) http.HandlerFunc {
+	return listCalendarTokens(db, models.CalendarDoctor, doctorCalendarOwner)
+}
+
+func CreateDoctorCalendarToken(db *gorm.DB) http.HandlerFunc {
+	return issueCalendarToken(db, models.CalendarDoctor, doctorCalendarOwner)
+}
+
+func RevokeDoctorCalendarToken(db *gorm.DB) http.HandlerFunc {
+	return revokeCalendarToken(db, models.CalendarDoctor, doctorCalendarOwner)
+}
+
+func GetPatientCalendarTokens(db *gorm.DB) http.HandlerFunc {
+	return listCalendarTokens(db, models.CalendarPatient, patientCalendarOwner)
+}
+
+func CreatePatientCalendarToken(db *gorm.DB) http.HandlerFunc {
+	return issueCalendarToken(db, models.CalendarPatient, patientCalendarOwner)
+}
+
+func RevokePatientCalendarToken(db *gorm.DB) http.HandlerFunc {
+	return revokeCalendarToken(db, models.CalendarPatient, patientCalendarOwner)
+}
+
+// GetCalendarFeed serves the iCalendar feed a token opens. It is outside
+// the authenticated API, since calendar clients cannot sign in; the token
+// in the URL is the credential.
+func GetCalendarFeed(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		t, err := calendar.Resolve(db, mux.Vars(r)["token"])
+		if err != nil {
+			if errors.Is(err, calendar.ErrTokenNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Calendar feed not found"))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to open calendar feed", err))
+			return
+		}
+		feed, err := calendar.Feed(db, t.Owner, t.OwnerID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to build calendar feed", err))
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		w.Write(feed)
+	}
+}
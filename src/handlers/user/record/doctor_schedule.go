@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/services/schedules"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// defaultRangeDays is how far ahead schedule and availability look when
+// ?to= is not given.
+const defaultRangeDays = 7
+
+// dateRange reads ?from= and ?to= as dates in the clinic. from defaults to
+// today and to to defaultRangeDays later.
+func dateRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	from := clinic.Today()
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, _, err := query.ParseTime(v)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "from must be YYYY-MM-DD or RFC 3339"))
+			return time.Time{}, time.Time{}, false
+		}
+		from = clinic.In(t)
+	}
+	to := from.AddDate(0, 0, defaultRangeDays)
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, _, err := query.ParseTime(v)
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "to must be YYYY-MM-DD or RFC 3339"))
+			return time.Time{}, time.Time{}, false
+		}
+		to = clinic.In(t)
+	}
+	return from, to, true
+}
+
+// GetDoctorSchedule returns the weekly windows plus the overrides and leave
+// between ?from= and ?to=.
+func GetDoctorSchedule(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doctor, ok := DoctorFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		from, to, ok := dateRange(w, r)
+		if !ok {
+			return
+		}
+		schedule, err := schedules.Load(db, int(doctor.DID), from, to)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch schedule", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schedule)
+	}
+}
+
+// PutDoctorWeeklySchedule replaces all of the doctor's weekly windows. An
+// empty list clears them.
+func PutDoctorWeeklySchedule(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doctor, ok := DoctorFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Windows []models.DoctorSchedule `json:"windows" validate:"dive"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if err := schedules.ReplaceWeekly(db, int(doctor.DID), input.Windows); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to save schedule", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"d_id":    doctor.DID,
+			"windows": input.Windows,
+		})
+	}
+}
+
+// CreateDoctorOverride sets the hours for one date, or closes it.
+func CreateDoctorOverride(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doctor, ok := DoctorFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var override models.DoctorScheduleOverride
+		if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(override); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		override.ID = 0
+		override.DID = int(doctor.DID)
+		override.Date = time.Date(override.Date.Year(), override.Date.Month(), override.Date.Day(), 0, 0, 0, 0, time.UTC)
+		if override.Closed {
+			override.StartTime, override.EndTime = "", ""
+		}
+
+		if err := db.Create(&override).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to save override", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(override)
+	}
+}
+
+func DeleteDoctorOverride(db *gorm.DB) http.HandlerFunc {
+	return deleteDoctorScheduleRow(db, &models.DoctorScheduleOverride{}, "override_id", "Override")
+}
+
+// CreateDoctorLeave records leave or a holiday covering whole days.
+func CreateDoctorLeave(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doctor, ok := DoctorFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var leave models.DoctorLeave
+		if err := json.NewDecoder(r.Body).Decode(&leave); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(leave); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		leave.ID = 0
+		leave.DID = int(doctor.DID)
+		leave.Kind = strings.ToLower(leave.Kind)
+		if leave.Kind == "" {
+			leave.Kind = "leave"
+		}
+		leave.CreatedBy, _ = middleware.UserIDFromContext(r.Context())
+
+		if err := db.Create(&leave).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to save leave", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(leave)
+	}
+}
+
+func DeleteDoctorLeave(db *gorm.DB) http.HandlerFunc {
+	return deleteDoctorScheduleRow(db, &models.DoctorLeave{}, "leave_id", "Leave")
+}
+
+// deleteDoctorScheduleRow deletes the row of model named by the idVar route
+// variable, provided it belongs to the {id} doctor.
+func deleteDoctorScheduleRow(db *gorm.DB, model interface{}, idVar, name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doctor, ok := DoctorFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(mux.Vars(r)[idVar])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid "+strings.ToLower(name)+" ID"))
+			return
+		}
+		result := db.Where("id = ? AND d_id = ?", id, doctor.DID).Delete(model)
+		if result.Error != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to delete "+strings.ToLower(name), result.Error))
+			return
+		}
+		if result.RowsAffected == 0 {
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, name+" not found"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetDoctorAvailability lists free slots between ?from= and ?to=, both
+// inclusive dates.
+func GetDoctorAvailability(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doctor, ok := DoctorFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		from, to, ok := dateRange(w, r)
+		if !ok {
+			return
+		}
+
+		slots, err := schedules.Availability(db, *doctor, from, to)
+		if err != nil {
+			switch {
+			case errors.Is(err, schedules.ErrRangeOrder):
+				apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, err.Error()))
+			case errors.Is(err, schedules.ErrRangeTooLong):
+				apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "from and to must be at most "+strconv.Itoa(schedules.MaxRangeDays)+" days apart"))
+			default:
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to compute availability", err))
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"d_id":  doctor.DID,
+			"from":  from.Format("2006-01-02"),
+			"to":    to.Format("2006-01-02"),
+			"slots": slots,
+		})
+	}
+}
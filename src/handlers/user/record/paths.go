@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// patientFromPath loads the live (not merged) patient named by the {p_id}
+// route variable, writing the error response itself when it fails.
+func patientFromPath(db *gorm.DB, w http.ResponseWriter, r *http.Request) (*models.Patient, bool) {
+	pid, err := strconv.Atoi(mux.Vars(r)["p_id"])
+	if err != nil || pid <= 0 {
+		apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid patient ID"))
+		return nil, false
+	}
+	var patient models.Patient
+	if err := db.Where("p_id = ?", pid).First(&patient).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Patient not found"))
+		} else {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to retrieve patient", err))
+		}
+		return nil, false
+	}
+	if patient.MergedInto != nil {
+		apierror.Write(w, r, apierror.New(apierror.CodeConflict, "Patient was merged into p_id "+strconv.Itoa(int(*patient.MergedInto))))
+		return nil, false
+	}
+	return &patient, true
+}
+
+// DoctorFromPath loads the doctor whose row is {id}, writing the error
+// response itself when it fails. The appointment handlers share it.
+func DoctorFromPath(db *gorm.DB, w http.ResponseWriter, r *http.Request) (*models.Doctor, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid doctor ID"))
+		return nil, false
+	}
+	var doctor models.Doctor
+	if err := db.Where("id = ?", id).First(&doctor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Doctor not found"))
+		} else {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to retrieve doctor", err))
+		}
+		return nil, false
+	}
+	return &doctor, true
+}
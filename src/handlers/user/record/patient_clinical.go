@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Allergies and problems are never deleted. Entries recorded by mistake are
+// updated to status entered_in_error instead.
+
+// withStatus narrows a clinical list to ?status= (comma separated) when given.
+func withStatus(db *gorm.DB, r *http.Request) *gorm.DB {
+	raw := strings.TrimSpace(r.URL.Query().Get("status"))
+	if raw == "" {
+		return db
+	}
+	var statuses []string
+	for _, s := range strings.Split(raw, ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			statuses = append(statuses, s)
+		}
+	}
+	return db.Where("lower(status) IN ?", statuses)
+}
+
+func GetPatientAllergies(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var allergies []models.PatientAllergy
+		if err := withStatus(db.Where("p_id = ?", patient.PID), r).
+			Order("created_at DESC").
+			Find(&allergies).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch allergies", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"p_id":      patient.PID,
+			"allergies": allergies,
+		})
+	}
+}
+
+func CreatePatientAllergy(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var allergy models.PatientAllergy
+		if err := json.NewDecoder(r.Body).Decode(&allergy); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		allergy.ID = 0
+		allergy.PID = patient.PID
+		allergy.Substance = strings.TrimSpace(allergy.Substance)
+		if allergy.Status == "" {
+			allergy.Status = "active"
+		}
+		allergy.RecordedBy, _ = middleware.UserIDFromContext(r.Context())
+		if err := validation.Struct(allergy); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if err := db.Create(&allergy).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to create allergy", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(allergy)
+	}
+}
+
+func UpdatePatientAllergy(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid allergy ID"))
+			return
+		}
+
+		var existing models.PatientAllergy
+		if err := db.Where("id = ? AND p_id = ?", id, patient.PID).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Allergy not found"))
+			} else {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch allergy", err))
+			}
+			return
+		}
+
+		var allergy models.PatientAllergy
+		if err := json.NewDecoder(r.Body).Decode(&allergy); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		allergy.ID = existing.ID
+		allergy.PID = patient.PID
+		allergy.RecordedBy = existing.RecordedBy
+		allergy.CreatedAt = existing.CreatedAt
+		allergy.Substance = strings.TrimSpace(allergy.Substance)
+		if allergy.Status == "" {
+			allergy.Status = existing.Status
+		}
+		if err := validation.Struct(allergy); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if err := db.Save(&allergy).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to update allergy", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(allergy)
+	}
+}
+
+func GetPatientProblems(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var problems []models.PatientProblem
+		if err := withStatus(db.Where("p_id = ?", patient.PID), r).
+			Order("onset_date DESC NULLS LAST, created_at DESC").
+			Find(&problems).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch problems", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"p_id":     patient.PID,
+			"problems": problems,
+		})
+	}
+}
+
+func CreatePatientProblem(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var problem models.PatientProblem
+		if err := json.NewDecoder(r.Body).Decode(&problem); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		problem.ID = 0
+		problem.PID = patient.PID
+		if problem.Status == "" {
+			problem.Status = "active"
+		}
+		problem.RecordedBy, _ = middleware.UserIDFromContext(r.Context())
+		if err := validation.Struct(problem); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if err := db.Create(&problem).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to create problem", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(problem)
+	}
+}
+
+func UpdatePatientProblem(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid problem ID"))
+			return
+		}
+
+		var existing models.PatientProblem
+		if err := db.Where("id = ? AND p_id = ?", id, patient.PID).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Problem not found"))
+			} else {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch problem", err))
+			}
+			return
+		}
+
+		var problem models.PatientProblem
+		if err := json.NewDecoder(r.Body).Decode(&problem); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		problem.ID = existing.ID
+		problem.PID = patient.PID
+		problem.RecordedBy = existing.RecordedBy
+		problem.CreatedAt = existing.CreatedAt
+		if problem.Status == "" {
+			problem.Status = existing.Status
+		}
+		if err := validation.Struct(problem); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if err := db.Save(&problem).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to update problem", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(problem)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/patients"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// GetPatientConsents returns the current decision per type and scope and the
+// full history, newest first. ?type= narrows both.
+func GetPatientConsents(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		consentType := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("type")))
+
+		current, err := patients.CurrentConsents(db, patient.PID)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch consents", err))
+			return
+		}
+		history := db.Where("p_id = ?", patient.PID)
+		if consentType != "" {
+			filtered := current[:0]
+			for _, c := range current {
+				if c.Type == consentType {
+					filtered = append(filtered, c)
+				}
+			}
+			current = filtered
+			history = history.Where("type = ?", consentType)
+		}
+		var consents []models.PatientConsent
+		if err := history.Order("given_at DESC, id DESC").Find(&consents).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch consents", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"p_id":    patient.PID,
+			"current": current,
+			"history": consents,
+		})
+	}
+}
+
+// CreatePatientConsent records a granted or refused consent. To take consent
+// back, withdraw the granted row instead.
+func CreatePatientConsent(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var consent models.PatientConsent
+		if err := json.NewDecoder(r.Body).Decode(&consent); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(consent); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		if err := patients.RecordConsent(db, patient.PID, &consent, userID); err != nil {
+			if errors.Is(err, patients.ErrConsentStatusOnSave) {
+				apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "status", Code: "consent_status", Message: err.Error()}))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to record consent", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(consent)
+	}
+}
+
+func WithdrawPatientConsent(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid consent ID"))
+			return
+		}
+
+		var input struct {
+			Reason string `json:"reason" validate:"required,max=500"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		if err := validation.Struct(input); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		userID, _ := middleware.UserIDFromContext(r.Context())
+		consent, err := patients.WithdrawConsent(db, patient.PID, id, userID, input.Reason)
+		if err != nil {
+			switch {
+			case errors.Is(err, patients.ErrConsentNotFound):
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Consent not found"))
+			case errors.Is(err, patients.ErrConsentNotGranted):
+				apierror.Write(w, r, apierror.New(apierror.CodeConflict, err.Error()))
+			default:
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to withdraw consent", err))
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(consent)
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/patients"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// contactLinkError maps a patients.PrepareContact failure to an API error.
+func contactLinkError(err error) error {
+	switch {
+	case errors.Is(err, patients.ErrSelfLink), errors.Is(err, patients.ErrLinkNotFound), errors.Is(err, patients.ErrLinkMerged):
+		return apierror.Validation(apierror.FieldError{Field: "linked_p_id", Code: "linked_patient", Message: err.Error()})
+	default:
+		return apierror.Wrap(apierror.CodeInternal, "Failed to resolve linked patient", err)
+	}
+}
+
+// GetPatientContacts lists the patient's related persons, and the patients
+// who list this patient as one of theirs.
+func GetPatientContacts(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var contacts []models.PatientContact
+		if err := db.Where("p_id = ?", patient.PID).
+			Order("is_emergency DESC, is_legal_guardian DESC, name").
+			Find(&contacts).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch contacts", err))
+			return
+		}
+
+		var linkedFrom []models.PatientContact
+		if err := db.Where("linked_p_id = ?", patient.PID).Order("p_id").Find(&linkedFrom).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch contacts", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"p_id":        patient.PID,
+			"contacts":    contacts,
+			"linked_from": linkedFrom,
+		})
+	}
+}
+
+func CreatePatientContact(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var contact models.PatientContact
+		if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		contact.ID = 0
+		contact.PID = patient.PID
+		if err := patients.PrepareContact(db, patient.PID, &contact); err != nil {
+			apierror.Write(w, r, contactLinkError(err))
+			return
+		}
+		if err := validation.Struct(contact); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if err := db.Create(&contact).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to create contact", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(contact)
+	}
+}
+
+func UpdatePatientContact(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid contact ID"))
+			return
+		}
+
+		var existing models.PatientContact
+		if err := db.Where("id = ? AND p_id = ?", id, patient.PID).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Contact not found"))
+			} else {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch contact", err))
+			}
+			return
+		}
+
+		var contact models.PatientContact
+		if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		contact.ID = existing.ID
+		contact.PID = patient.PID
+		contact.CreatedAt = existing.CreatedAt
+		if err := patients.PrepareContact(db, patient.PID, &contact); err != nil {
+			apierror.Write(w, r, contactLinkError(err))
+			return
+		}
+		if err := validation.Struct(contact); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// Save writes every column, so cleared flags and links are persisted.
+		if err := db.Save(&contact).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to update contact", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(contact)
+	}
+}
+
+func DeletePatientContact(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid contact ID"))
+			return
+		}
+
+		result := db.Where("id = ? AND p_id = ?", id, patient.PID).Delete(&models.PatientContact{})
+		if result.Error != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to delete contact", result.Error))
+			return
+		}
+		if result.RowsAffected == 0 {
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Contact not found"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
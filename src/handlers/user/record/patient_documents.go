@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/documents"
+	"github.com/PragaL15/med_admin_backend/src/storage"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// multipartOverhead is the room allowed for form fields and boundaries on
+// top of the document size limit.
+const multipartOverhead = 1 << 20
+
+// documentFromPath loads the {id} document of the {p_id} patient.
+func documentFromPath(db *gorm.DB, w http.ResponseWriter, r *http.Request) (*models.Document, bool) {
+	patient, ok := patientFromPath(db, w, r)
+	if !ok {
+		return nil, false
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid document ID"))
+		return nil, false
+	}
+	var doc models.Document
+	if err := db.Where("id = ? AND p_id = ?", id, patient.PID).First(&doc).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Document not found"))
+		} else {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch document", err))
+		}
+		return nil, false
+	}
+	return &doc, true
+}
+
+// GetPatientDocuments lists document metadata, newest first. ?record_id=
+// and ?category= narrow the list.
+func GetPatientDocuments(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		q := db.Where("p_id = ?", patient.PID)
+		if v := r.URL.Query().Get("record_id"); v != "" {
+			recordID, err := strconv.Atoi(v)
+			if err != nil {
+				apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid record_id"))
+				return
+			}
+			q = q.Where("record_id = ?", recordID)
+		}
+		if v := r.URL.Query().Get("category"); v != "" {
+			q = q.Where("category = ?", strings.ToLower(v))
+		}
+
+		var docs []models.Document
+		if err := q.Order("created_at DESC").Find(&docs).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch documents", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"p_id":      patient.PID,
+			"documents": docs,
+		})
+	}
+}
+
+// UploadPatientDocument accepts multipart/form-data with a "file" part and
+// optional "record_id", "category" and "description" fields.
+func UploadPatientDocument(db *gorm.DB, store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, documents.MaxBytes()+multipartOverhead)
+		if err := r.ParseMultipartForm(multipartOverhead); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "file", Code: "max", Message: documents.ErrTooLarge.Error()}))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidParameter, "Expected a multipart/form-data body", err))
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "file", Code: "required", Message: "is required"}))
+			return
+		}
+		defer file.Close()
+
+		doc := models.Document{
+			PID:         patient.PID,
+			Category:    strings.ToLower(r.FormValue("category")),
+			FileName:    filepath.Base(header.Filename),
+			Description: r.FormValue("description"),
+		}
+		if doc.Category == "" {
+			doc.Category = "other"
+		}
+		if v := r.FormValue("record_id"); v != "" {
+			recordID, err := strconv.Atoi(v)
+			if err != nil {
+				apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "record_id", Code: "number", Message: "must be a record ID"}))
+				return
+			}
+			doc.RecordID = &recordID
+		}
+		doc.UploadedBy, _ = middleware.UserIDFromContext(r.Context())
+		if err := validation.Struct(doc); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if err := documents.Upload(r.Context(), db, store, &doc, file); err != nil {
+			switch {
+			case errors.Is(err, documents.ErrTooLarge), errors.Is(err, documents.ErrEmpty), errors.Is(err, documents.ErrUnsupportedType):
+				apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "file", Code: "document", Message: err.Error()}))
+			case errors.Is(err, documents.ErrRecordNotOfPatient):
+				apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "record_id", Code: "record", Message: err.Error()}))
+			default:
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to store document", err))
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(doc)
+	}
+}
+
+// DownloadPatientDocument streams the stored bytes. The checksum doubles as
+// the ETag.
+func DownloadPatientDocument(db *gorm.DB, store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doc, ok := documentFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		etag := `"` + doc.SHA256 + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		body, err := store.Get(r.Context(), doc.StorageKey)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Document content is missing from storage", err))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to read document", err))
+			return
+		}
+		defer body.Close()
+
+		w.Header().Set("Content-Type", doc.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(doc.Size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": doc.FileName}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("ETag", etag)
+		if _, err := io.Copy(w, body); err != nil {
+			log.Printf("Error streaming document %d: %v", doc.ID, err)
+		}
+	}
+}
+
+func DeletePatientDocument(db *gorm.DB, store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		doc, ok := documentFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		if err := documents.Delete(r.Context(), db, store, doc); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to delete document", err))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetRecordDocuments lists the documents attached to one record.
+func GetRecordDocuments(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid ID"))
+			return
+		}
+		var record models.Record
+		if err := db.Select("id", "p_id").First(&record, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Record not found"))
+			} else {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch record", err))
+			}
+			return
+		}
+
+		var docs []models.Document
+		if err := db.Where("record_id = ?", record.ID).Order("created_at DESC").Find(&docs).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch documents", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"record_id": record.ID,
+			"p_id":      record.PID,
+			"documents": docs,
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/patients"
+	"gorm.io/gorm"
+)
+
+// ExportPatient returns everything held about the patient as one JSON
+// document. It is refused when the patient's data_sharing consent, in the
+// general scope or the ?scope= given (for example the receiving
+// organisation), is refused, withdrawn or expired.
+func ExportPatient(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		if err := patients.CheckConsent(db, patient.PID, models.ConsentDataSharing, r.URL.Query().Get("scope")); err != nil {
+			var denied *patients.ConsentDeniedError
+			if errors.As(err, &denied) {
+				apierror.Write(w, r, apierror.New(apierror.CodeConsentRequired, "Data export blocked: "+denied.Error()))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to check consent", err))
+			return
+		}
+
+		export := struct {
+			ExportedAt   time.Time                  `json:"exported_at"`
+			Patient      models.Patient             `json:"patient"`
+			Identifiers  []models.PatientIdentifier `json:"identifiers"`
+			Contacts     []models.PatientContact    `json:"contacts"`
+			Insurance    []models.PatientInsurance  `json:"insurance"`
+			Consents     []models.PatientConsent    `json:"consents"`
+			Allergies    []models.PatientAllergy    `json:"allergies"`
+			Problems     []models.PatientProblem    `json:"problems"`
+			Records      []models.Record            `json:"records"`
+			Appointments []models.Appointment       `json:"appointments"`
+			Admissions   []models.Admitted          `json:"admissions"`
+			Vitals       []models.VitalSign         `json:"vitals"`
+			Documents    []models.Document          `json:"documents"`
+		}{ExportedAt: time.Now(), Patient: *patient}
+
+		for _, part := range []struct {
+			name string
+			dest interface{}
+		}{
+			{"identifiers", &export.Identifiers},
+			{"contacts", &export.Contacts},
+			{"insurance", &export.Insurance},
+			{"consents", &export.Consents},
+			{"allergies", &export.Allergies},
+			{"problems", &export.Problems},
+			{"records", &export.Records},
+			{"appointments", &export.Appointments},
+			{"admissions", &export.Admissions},
+			{"vitals", &export.Vitals},
+			{"documents", &export.Documents},
+		} {
+			if err := db.Where("p_id = ?", patient.PID).Order("id").Find(part.dest).Error; err != nil {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to export "+part.name, err))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="patient-%d.json"`, patient.PID))
+		json.NewEncoder(w).Encode(export)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PragaL15/med_admin_backend/database"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func GetPatientIdentifiers(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var identifiers []models.PatientIdentifier
+		if err := db.Where("p_id = ?", patient.PID).Order("type, issuer").Find(&identifiers).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch identifiers", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"p_id":        patient.PID,
+			"mrn":         patient.MRN,
+			"identifiers": identifiers,
+		})
+	}
+}
+
+func CreatePatientIdentifier(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var identifier models.PatientIdentifier
+		if err := json.NewDecoder(r.Body).Decode(&identifier); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		identifier.ID = 0
+		identifier.PID = patient.PID
+		identifier.Issuer = strings.TrimSpace(identifier.Issuer)
+		identifier.Value = strings.TrimSpace(identifier.Value)
+		if err := validation.Struct(identifier); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if err := db.Create(&identifier).Error; err != nil {
+			if database.IsUniqueViolation(err) {
+				apierror.Write(w, r, apierror.New(apierror.CodeConflict, "This issuer has already assigned that value to a patient"))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to create identifier", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(identifier)
+	}
+}
+
+func DeletePatientIdentifier(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid identifier ID"))
+			return
+		}
+
+		result := db.Where("id = ? AND p_id = ?", id, patient.PID).Delete(&models.PatientIdentifier{})
+		if result.Error != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to delete identifier", result.Error))
+			return
+		}
+		if result.RowsAffected == 0 {
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Identifier not found"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// LookupPatientByIdentifier resolves ?issuer=&value= to a patient.
+func LookupPatientByIdentifier(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		issuer := strings.TrimSpace(r.URL.Query().Get("issuer"))
+		value := strings.TrimSpace(r.URL.Query().Get("value"))
+		if issuer == "" || value == "" {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "issuer and value are required"))
+			return
+		}
+
+		var patient models.Patient
+		err := db.Joins("JOIN patient_identifiers ON patient_identifiers.p_id = patient_id.p_id").
+			Where("patient_identifiers.issuer = ? AND patient_identifiers.value = ?", issuer, value).
+			First(&patient).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "No patient has this identifier"))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to look up identifier", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(patient)
+	}
+}
@@ -1,187 +1,65 @@
-// package handlers
-
-// import (
-// 	"encoding/json"
-// 	"log"
-// 	"net/http"
-// 	"strconv"
-// 	"time"
-// 	"github.com/gorilla/mux"
-// 	models "github.com/PragaL15/med_admin_backend/src/model"
-//     "gorm.io/gorm"
-
-// )
-
-// func CreatePatient(db *gorm.DB) http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-// 		var patient models.Patient
-// 		if err := json.NewDecoder(r.Body).Decode(&patient); err != nil {
-// 			http.Error(w, "Invalid request body", http.StatusBadRequest)
-// 			return
-// 		}
-
-// 		patient.CreatedAt = time.Now()
-// 		patient.UpdatedAt = time.Now()
-// 		if err := db.Create(&patient).Error; err != nil {
-// 			log.Println("Error creating patient:", err)
-// 			http.Error(w, "Failed to create patient", http.StatusInternalServerError)
-// 			return
-// 		}
-// 		w.WriteHeader(http.StatusCreated)
-// 		json.NewEncoder(w).Encode(patient)
-// 	}
-// }
-// func GetAllPatients(db *gorm.DB) http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-// 		var patients []models.Patient
-
-// 		if err := db.Find(&patients).Error; err != nil {
-// 			log.Println("Error retrieving patients:", err)
-// 			http.Error(w, "Failed to retrieve patients", http.StatusInternalServerError)
-// 			return
-// 		}
-
-// 		w.Header().Set("Content-Type", "application/json")
-// 		json.NewEncoder(w).Encode(patients)
-// 	}
-// }
-
-// func GetPatientByID(db *gorm.DB) http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-// 		vars := mux.Vars(r)
-// 		id, err := strconv.Atoi(vars["id"])
-// 		if err != nil {
-// 			http.Error(w, "Invalid patient ID", http.StatusBadRequest)
-// 			return
-// 		}
-
-// 		var patient models.Patient
-// 		if err := db.First(&patient, id).Error; err != nil {
-// 			if err.Error() == "record not found" {
-// 				http.Error(w, "Patient not found", http.StatusNotFound)
-// 			} else {
-// 				log.Println("Error retrieving patient:", err)
-// 				http.Error(w, "Failed to retrieve patient", http.StatusInternalServerError)
-// 			}
-// 			return
-// 		}
-
-// 		w.Header().Set("Content-Type", "application/json")
-// 		json.NewEncoder(w).Encode(patient)
-// 	}
-// }
-
-// func UpdatePatient(db *gorm.DB) http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-// 		vars := mux.Vars(r)
-// 		id, err := strconv.Atoi(vars["id"])
-// 		if err != nil {
-// 			http.Error(w, "Invalid patient ID", http.StatusBadRequest)
-// 			return
-// 		}
-
-// 		var patient models.Patient
-// 		if err := json.NewDecoder(r.Body).Decode(&patient); err != nil {
-// 			http.Error(w, "Invalid request body", http.StatusBadRequest)
-// 			return
-// 		}
-
-// 		patient.UpdatedAt = time.Now()
-
-// 		if err := db.Model(&patient).Where("id = ?", id).Updates(map[string]interface{}{
-// 			"p_id":       patient.PID,
-// 			"p_name":     patient.Name,
-// 			"p_number":   patient.Phone,
-// 			"p_email":    patient.Email,
-// 			"p_status":   patient.Status,
-// 			"updatedat":  patient.UpdatedAt,
-// 			"p_address":  patient.Address,
-// 			"p_mode":     patient.Mode,
-// 			"p_age":      patient.Age,
-// 			"p_gender":   patient.Gender,
-// 		}).Error; err != nil {
-// 			log.Println("Error updating patient:", err)
-// 			http.Error(w, "Failed to update patient", http.StatusInternalServerError)
-// 			return
-// 		}
-
-// 		w.WriteHeader(http.StatusOK)
-// 		json.NewEncoder(w).Encode(map[string]string{"message": "Patient updated successfully"})
-// 	}
-// }
-
-// func DeletePatient(db *gorm.DB) http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-// 		vars := mux.Vars(r)
-// 		id, err := strconv.Atoi(vars["id"])
-// 		if err != nil {
-// 			http.Error(w, "Invalid patient ID", http.StatusBadRequest)
-// 			return
-// 		}
-
-// 		result := db.Where("id = ?", id).Delete(&models.Patient{})
-// 		if result.Error != nil {
-// 			log.Println("Error deleting patient:", result.Error)
-// 			http.Error(w, "Failed to delete patient", http.StatusInternalServerError)
-// 			return
-// 		}
-
-// 		if result.RowsAffected == 0 {
-// 			http.Error(w, "Patient not found", http.StatusNotFound)
-// 			return
-// 		}
-
-// 		w.WriteHeader(http.StatusOK)
-// 		json.NewEncoder(w).Encode(map[string]string{"message": "Patient deleted successfully"})
-// 	}
-// }
-
-
 package handlers
 
 import (
+	"errors"
 	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 	"github.com/gorilla/mux"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/validation"
 	"gorm.io/gorm"
 )
 
-func CreatePatient(db *gorm.DB) http.HandlerFunc {
+var patientListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"p_id":       {Column: "p_id", Kind: query.Equals, Type: query.Int},
+		"status":     {Column: "p_status", Kind: query.Equals},
+		"gender":     {Column: "p_gender", Kind: query.Equals},
+		"mode":       {Column: "p_mode", Kind: query.Equals},
+		"dob":        {Column: "dob", Kind: query.DateRange},
+		"created_at": {Column: "createdat", Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":         {Column: "id", Field: "ID"},
+		"p_id":       {Column: "p_id", Field: "PID"},
+		"name":       {Column: "p_name", Field: "Name"},
+		"age":        {Column: "p_age", Field: "Age"},
+		"dob":        {Column: "dob", Field: "DOB"},
+		"created_at": {Column: "createdat", Field: "CreatedAt"},
+	},
+	DefaultSort: "p_id",
+	Key:         "id",
+}
+
+func GetAllPatients(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var patient models.Patient
-		if err := json.NewDecoder(r.Body).Decode(&patient); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		db := db.WithContext(r.Context())
+		params, err := query.Parse(r, patientListSpec)
+		if err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
-		patient.CreatedAt = time.Now()
-		patient.UpdatedAt = time.Now()
-		if err := db.Create(&patient).Error; err != nil {
-			log.Println("Error creating patient:", err)
-			http.Error(w, "Failed to create patient", http.StatusInternalServerError)
+		base := params.Filter(db.Model(&models.Patient{})).Session(&gorm.Session{})
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count patients", err))
 			return
 		}
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(patient)
-	}
-}
 
-func GetAllPatients(db *gorm.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
 		var patients []models.Patient
-
-		if err := db.Find(&patients).Error; err != nil {
+		if err := params.Page(base).Find(&patients).Error; err != nil {
 			log.Println("Error retrieving patients:", err)
-			http.Error(w, "Failed to retrieve patients", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to retrieve patients", err))
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(patients)
+		query.WriteList(w, r, patients, params.Finish(&patients, total))
 	}
 }
 
@@ -189,20 +67,21 @@ func GetAllPatients(db *gorm.DB) http.HandlerFunc {
 // Get patient by dynamic p_id
 func GetPatientByID(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		vars := mux.Vars(r)
 		p_id, err := strconv.Atoi(vars["p_id"]) 
 		if err != nil {
-			http.Error(w, "Invalid patient ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid patient ID"))
 			return
 		}
 
 		var patient models.Patient
 		if err := db.Where("p_id = ?", p_id).First(&patient).Error; err != nil {
-			if err.Error() == "record not found" {
-				http.Error(w, "Patient not found", http.StatusNotFound)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Patient not found"))
 			} else {
 				log.Println("Error retrieving patient:", err)
-				http.Error(w, "Failed to retrieve patient", http.StatusInternalServerError)
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to retrieve patient", err))
 			}
 			return
 		}
@@ -214,23 +93,29 @@ func GetPatientByID(db *gorm.DB) http.HandlerFunc {
 
 func UpdatePatient(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"]) 
 		if err != nil {
-			http.Error(w, "Invalid patient ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid patient ID"))
 			return
 		}
 
 		var patient models.Patient
 		if err := json.NewDecoder(r.Body).Decode(&patient); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		// Only the columns written below are validated; DOB, occupation and
+		// language are not part of this update.
+		if err := validation.StructExcept(patient, "DOB", "Occupation", "Language"); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
 		patient.UpdatedAt = time.Now()
 
 		if err := db.Model(&patient).Where("id = ?", id).Updates(map[string]interface{}{
-			"p_id":       patient.PID,
 			"p_name":     patient.Name,
 			"p_number":   patient.Phone,
 			"p_email":    patient.Email,
@@ -242,7 +127,7 @@ func UpdatePatient(db *gorm.DB) http.HandlerFunc {
 			"p_gender":   patient.Gender,
 		}).Error; err != nil {
 			log.Println("Error updating patient:", err)
-			http.Error(w, "Failed to update patient", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to update patient", err))
 			return
 		}
 
@@ -253,22 +138,23 @@ func UpdatePatient(db *gorm.DB) http.HandlerFunc {
 
 func DeletePatient(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"]) 
 		if err != nil {
-			http.Error(w, "Invalid patient ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid patient ID"))
 			return
 		}
 
 		result := db.Where("id = ?", id).Delete(&models.Patient{})
 		if result.Error != nil {
 			log.Println("Error deleting patient:", result.Error)
-			http.Error(w, "Failed to delete patient", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to delete patient", result.Error))
 			return
 		}
 
 		if result.RowsAffected == 0 {
-			http.Error(w, "Patient not found", http.StatusNotFound)
+			apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Patient not found"))
 			return
 		}
 
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"gorm.io/gorm"
+)
+
+const (
+	searchDefaultLimit = 20
+	searchMaxLimit     = 100
+	searchMinLength    = 2
+)
+
+var (
+	nonDigits  = regexp.MustCompile(`\D`)
+	likeEscape = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+type PatientMatch struct {
+	models.Patient
+	Score float64 `json:"score"`
+}
+
+// patientSearchSQL matches on full text, trigram similarity (typos), word
+// similarity (partial names), double metaphone (transliterated spellings such
+// as Mohammed/Muhammad), phone digits and exact p_id or MRN, and ranks by the best
+// of those signals. Patients merged into another record are left out. The
+// p_id and MRN lookups use their unique indexes and every other predicate an
+// index from migration 0001_patient_search; @like is the term escaped for
+// LIKE, so % and _ typed by the user match themselves.
+const patientSearchSQL = searchTermsSQL + `
+SELECT p.*,
+	GREATEST(
+		CASE WHEN p.p_id = @pid OR upper(p.mrn) = upper(q.term) THEN 1.0 ELSE 0 END,
+		CASE WHEN @digits <> '' AND regexp_replace(p.p_number, '\D', '', 'g') LIKE '%' || @digits || '%' THEN 0.9 ELSE 0 END,
+		CASE WHEN p.p_email ILIKE @like || '%' ESCAPE '\' THEN 0.9 ELSE 0 END,
+		ts_rank(to_tsvector('simple', coalesce(p.p_name, '') || ' ' || coalesce(p.p_email, '') || ' ' || coalesce(p.p_address, '')), q.tsq),
+		similarity(p.p_name, q.term),
+		word_similarity(q.term, p.p_name),
+		similarity(p.p_email, q.term),
+		similarity(p.p_address, q.term) * 0.5,
+		CASE WHEN q.phon <> '' AND dmetaphone(p.p_name) = q.phon THEN 0.6 ELSE 0 END
+	) AS score` + searchMatchSQL + `
+ORDER BY score DESC, p.p_id
+LIMIT @limit`
+
+// patientSearchCountSQL counts every match of patientSearchSQL, for the
+// total in the response metadata.
+const patientSearchCountSQL = searchTermsSQL + `
+SELECT count(*)` + searchMatchSQL
+
+const searchTermsSQL = `
+WITH q AS (
+	SELECT @term::text AS term,
+	       plainto_tsquery('simple', @term) AS tsq,
+	       dmetaphone(@term) AS phon
+)`
+
+const searchMatchSQL = `
+FROM patient_id p, q
+WHERE p.merged_into_p_id IS NULL AND (
+      p.p_id = @pid
+   OR upper(p.mrn) = upper(q.term)
+   OR (@digits <> '' AND regexp_replace(p.p_number, '\D', '', 'g') LIKE '%' || @digits || '%')
+   OR to_tsvector('simple', coalesce(p.p_name, '') || ' ' || coalesce(p.p_email, '') || ' ' || coalesce(p.p_address, '')) @@ q.tsq
+   OR p.p_name % q.term
+   OR q.term <% p.p_name
+   OR p.p_email ILIKE '%' || @like || '%' ESCAPE '\'
+   OR p.p_address % q.term
+   OR (q.phon <> '' AND dmetaphone(p.p_name) = q.phon))`
+
+func SearchPatients(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		term := strings.TrimSpace(r.URL.Query().Get("q"))
+		if len([]rune(term)) < searchMinLength {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "q must be at least "+strconv.Itoa(searchMinLength)+" characters"))
+			return
+		}
+
+		limit := searchDefaultLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > searchMaxLimit {
+				apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(searchMaxLimit)))
+				return
+			}
+			limit = n
+		}
+
+		// Only treat the term as a phone fragment when it is mostly digits,
+		// so "Ward 3" does not match every number containing a 3.
+		digits := nonDigits.ReplaceAllString(term, "")
+		if len(digits) < 3 || len(digits)*2 < len(term) {
+			digits = ""
+		}
+
+		// A term that is a patient number is looked up by p_id.
+		var pid *int64
+		if n, err := strconv.ParseInt(term, 10, 32); err == nil && n > 0 {
+			pid = &n
+		}
+
+		args := map[string]interface{}{
+			"term":   term,
+			"like":   likeEscape.Replace(term),
+			"pid":    pid,
+			"digits": digits,
+			"limit":  limit,
+		}
+		var matches []PatientMatch
+		if err := db.Raw(patientSearchSQL, args).Scan(&matches).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to search patients", err))
+			return
+		}
+		// A short page holds every match; only a full one needs counting.
+		total := int64(len(matches))
+		if len(matches) == limit {
+			if err := db.Raw(patientSearchCountSQL, args).Scan(&total).Error; err != nil {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count patient matches", err))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(query.Page{
+			Data: matches,
+			Meta: query.Meta{Total: total, Limit: limit, Sort: "-score", HasMore: total > int64(len(matches))},
+		})
+	}
+}
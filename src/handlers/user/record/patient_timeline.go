@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/services/patients"
+	"gorm.io/gorm"
+)
+
+var timelineSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"type":        {Column: "type", Kind: query.Equals},
+		"d_id":        {Column: "d_id", Kind: query.Equals, Type: query.Int},
+		"occurred_at": {Column: "occurred_at", Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"key":         {Column: "entry_key", Field: "Key"},
+		"occurred_at": {Column: "occurred_at", Field: "OccurredAt"},
+	},
+	DefaultSort: "-occurred_at",
+	Key:         "key",
+}
+
+// GetPatientTimeline lists the patient's records, prescriptions,
+// appointments, admissions and operations in one stream, newest first.
+// ?type= takes a comma separated list of patients.TimelineTypes.
+func GetPatientTimeline(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		if v := r.URL.Query().Get("type"); v != "" {
+			for _, t := range strings.Split(v, ",") {
+				if !contains(patients.TimelineTypes, strings.TrimSpace(t)) {
+					apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "type must be one of: "+strings.Join(patients.TimelineTypes, ", ")))
+					return
+				}
+			}
+		}
+
+		params, err := query.Parse(r, timelineSpec)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		base := params.Filter(patients.Timeline(db, patient.PID)).Session(&gorm.Session{})
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count timeline entries", err))
+			return
+		}
+
+		var entries []patients.TimelineEntry
+		if err := params.Page(base).Find(&entries).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch timeline", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(query.Page{Data: entries, Meta: params.Finish(&entries, total)})
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/middleware"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/services/vitals"
+	"github.com/PragaL15/med_admin_backend/src/validation"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const defaultTrendWindow = 7 * 24 * time.Hour
+
+var vitalListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"admission_id": {Column: "admission_id", Kind: query.Equals, Type: query.Int},
+		"news2_risk":   {Column: "news2_risk", Kind: query.Equals},
+		"recorded_at":  {Column: "recorded_at", Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":          {Column: "id", Field: "ID"},
+		"recorded_at": {Column: "recorded_at", Field: "RecordedAt"},
+	},
+	DefaultSort: "-recorded_at",
+	Key:         "id",
+}
+
+// listVitals writes one page of the vital signs matched by scope.
+func listVitals(w http.ResponseWriter, r *http.Request, scope *gorm.DB) {
+	params, err := query.Parse(r, vitalListSpec)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	base := params.Filter(scope.Model(&models.VitalSign{})).Session(&gorm.Session{})
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count vital signs", err))
+		return
+	}
+
+	var rows []models.VitalSign
+	if err := params.Page(base).Find(&rows).Error; err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch vital signs", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(query.Page{Data: rows, Meta: params.Finish(&rows, total)})
+}
+
+func GetPatientVitals(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		listVitals(w, r, db.Where("p_id = ?", patient.PID))
+	}
+}
+
+// GetAdmissionVitals lists the vital signs recorded during one admission.
+func GetAdmissionVitals(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid admission ID"))
+			return
+		}
+		var admission models.Admitted
+		if err := db.Select("id").First(&admission, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Admission not found"))
+			} else {
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to retrieve admission", err))
+			}
+			return
+		}
+		listVitals(w, r, db.Where("admission_id = ?", admission.ID))
+	}
+}
+
+// CreatePatientVital records a set of observations. The NEWS2 score is
+// computed server side; any score in the body is ignored.
+func CreatePatientVital(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+
+		var vital models.VitalSign
+		if err := json.NewDecoder(r.Body).Decode(&vital); err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid request body", err))
+			return
+		}
+		vital.ID = 0
+		vital.PID = patient.PID
+		if vital.RecordedAt.IsZero() {
+			vital.RecordedAt = time.Now()
+		}
+		vital.RecordedBy, _ = middleware.UserIDFromContext(r.Context())
+		if err := validation.Struct(vital); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if err := vitals.Record(db, &vital); err != nil {
+			if errors.Is(err, vitals.ErrAdmissionMismatch) {
+				apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "admission_id", Code: "admission", Message: err.Error()}))
+				return
+			}
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to record vital signs", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(vital)
+	}
+}
+
+// GetPatientVitalTrend aggregates observations into ?bucket= (hour, day or
+// week) buckets between ?from= and ?to=, defaulting to the last seven days.
+// ?admission_id= narrows it to one admission.
+func GetPatientVitalTrend(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
+		patient, ok := patientFromPath(db, w, r)
+		if !ok {
+			return
+		}
+		q := r.URL.Query()
+
+		to := time.Now()
+		if v := strings.TrimSpace(q.Get("to")); v != "" {
+			t, dateOnly, err := query.ParseTime(v)
+			if err != nil {
+				apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "to must be YYYY-MM-DD or RFC 3339"))
+				return
+			}
+			if dateOnly {
+				t = t.AddDate(0, 0, 1)
+			}
+			to = t
+		}
+		from := to.Add(-defaultTrendWindow)
+		if v := strings.TrimSpace(q.Get("from")); v != "" {
+			t, _, err := query.ParseTime(v)
+			if err != nil {
+				apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "from must be YYYY-MM-DD or RFC 3339"))
+				return
+			}
+			from = t
+		}
+		if !from.Before(to) {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "from must be before to"))
+			return
+		}
+
+		bucket := strings.ToLower(strings.TrimSpace(q.Get("bucket")))
+		if bucket == "" {
+			bucket = "hour"
+		}
+		valid := false
+		for _, b := range vitals.Buckets {
+			valid = valid || b == bucket
+		}
+		if !valid {
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "bucket must be one of: "+strings.Join(vitals.Buckets, ", ")))
+			return
+		}
+
+		var admissionID *int
+		if v := strings.TrimSpace(q.Get("admission_id")); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid admission_id"))
+				return
+			}
+			admissionID = &id
+		}
+
+		points, err := vitals.Trend(db, patient.PID, admissionID, from, to, bucket)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to compute vital sign trend", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"p_id":            patient.PID,
+			"from":            from,
+			"to":              to,
+			"bucket":          bucket,
+			"alert_threshold": vitals.AlertThreshold(),
+			"points":          points,
+		})
+	}
+}
@@ -7,62 +7,115 @@ import (
 	"strconv"
 	"time"
 	"errors"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/query"
+	"github.com/PragaL15/med_admin_backend/src/services/patients"
+	"github.com/PragaL15/med_admin_backend/src/validation"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
+var recordListSpec = query.Spec{
+	Filters: map[string]query.Filter{
+		"p_id":       {Column: "p_id", Kind: query.Equals, Type: query.Int},
+		"d_id":       {Column: "d_id", Kind: query.Equals, Type: query.Int},
+		"date":       {Column: "date", Kind: query.DateRange},
+		"created_at": {Column: "created_at", Kind: query.DateRange},
+	},
+	Sorts: map[string]query.Sort{
+		"id":         {Column: "id", Field: "ID"},
+		"p_id":       {Column: "p_id", Field: "PID"},
+		"d_id":       {Column: "d_id", Field: "DID"},
+		"date":       {Column: "date", Field: "Date"},
+		"created_at": {Column: "created_at", Field: "CreatedAt"},
+	},
+	DefaultSort: "-date",
+	Key:         "id",
+}
+
 func GetRecords(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var records []models.Record
+		db := db.WithContext(r.Context())
+		params, err := query.Parse(r, recordListSpec)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
 
-		if err := db.Find(&records).Error; err != nil {
-			http.Error(w, "Failed to fetch records", http.StatusInternalServerError)
+		base := params.Filter(db.Model(&models.Record{})).Session(&gorm.Session{})
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to count records", err))
+			return
+		}
+
+		var records []models.Record
+		if err := params.Page(base).Find(&records).Error; err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch records", err))
 			log.Println("Database query error:", err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(records)
+		query.WriteList(w, r, records, params.Finish(&records, total))
 	}
 }
 
 func GetRecordByID(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid ID"))
 			return
 		}
 		var record models.Record
 		if err := db.First(&record, id).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
-				http.Error(w, "Record not found", http.StatusNotFound)
+				apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Record not found"))
 			} else {
-				http.Error(w, "Failed to fetch record", http.StatusInternalServerError)
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch record", err))
 			}
 			log.Println("Record fetch error:", err)
 			return
 		}
 
+		// The prescription view shows the patient's active allergies and
+		// problems, and flags allergies named in the prescription text.
+		clinical, err := patients.ActiveClinical(db, []uint{uint(record.PID)})
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to fetch allergies and problems", err))
+			return
+		}
+		summary := clinical[uint(record.PID)]
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(record)
+		json.NewEncoder(w).Encode(struct {
+			models.Record
+			*patients.Clinical
+			AllergyWarnings []models.PatientAllergy `json:"allergy_warnings"`
+		}{record, summary, patients.AllergyWarnings(summary.Allergies, record.Prescription)})
 	}
 }
 
 func CreateRecord(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		var record models.Record
 		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
-			http.Error(w, "Invalid input", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid input", err))
+			return
+		}
+		if err := validation.Struct(record); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 		record.CreatedAt = time.Now()
 		record.UpdatedAt = time.Now()
 
 		if err := db.Create(&record).Error; err != nil {
-			http.Error(w, "Failed to create record", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to create record", err))
 			log.Println("Record creation error:", err)
 			return
 		}
@@ -73,16 +126,21 @@ func CreateRecord(db *gorm.DB) http.HandlerFunc {
 }
 func UpdateRecord(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid ID"))
 			return
 		}
 
 		var record models.Record
 		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
-			http.Error(w, "Invalid input", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid input", err))
+			return
+		}
+		if err := validation.Struct(record); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
@@ -95,7 +153,7 @@ func UpdateRecord(db *gorm.DB) http.HandlerFunc {
 			"Prescription": record.Prescription,
 			"UpdatedAt":   record.UpdatedAt,
 		}).Error; err != nil {
-			http.Error(w, "Failed to update record", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to update record", err))
 			log.Println("Record update error:", err)
 			return
 		}
@@ -106,6 +164,7 @@ func UpdateRecord(db *gorm.DB) http.HandlerFunc {
 }
 func UpdateDescriptionByPID(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		
 			vars := mux.Vars(r)
 			idStr := vars["p_id"]
@@ -113,7 +172,7 @@ func UpdateDescriptionByPID(db *gorm.DB) http.HandlerFunc {
 			id, err := strconv.Atoi(idStr)
 			if err != nil {
 					log.Printf("Invalid patient ID: %v", err)
-					http.Error(w, "Invalid Patient ID", http.StatusBadRequest)
+					apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid Patient ID"))
 					return
 			}
 
@@ -124,18 +183,18 @@ func UpdateDescriptionByPID(db *gorm.DB) http.HandlerFunc {
 
 			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 					log.Printf("JSON decode error: %v", err)
-					http.Error(w, "Invalid input", http.StatusBadRequest)
+					apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid input", err))
 					return
 			}
 
 			if input.Pid != id {
 					log.Printf("Mismatch between p_id in URL (%d) and p_id in body (%d)", id, input.Pid)
-					http.Error(w, "Patient ID mismatch", http.StatusBadRequest)
+					apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Patient ID mismatch"))
 					return
 			}
 
 			if input.Description == "" {
-					http.Error(w, "Description cannot be empty", http.StatusBadRequest)
+					apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "description", Code: "required", Message: "Description cannot be empty"}))
 					return
 			}
 
@@ -143,17 +202,17 @@ func UpdateDescriptionByPID(db *gorm.DB) http.HandlerFunc {
 			if err := db.Where("p_id = ?", id).First(&record).Error; err != nil {
 					if errors.Is(err, gorm.ErrRecordNotFound) {
 							log.Printf("Record not found for p_id: %d", id)
-							http.Error(w, "Record not found", http.StatusNotFound)
+							apierror.Write(w, r, apierror.New(apierror.CodeNotFound, "Record not found"))
 					} else {
 							log.Printf("Database error: %v", err)
-							http.Error(w, "Database error", http.StatusInternalServerError)
+							apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Database error", err))
 					}
 					return
 			}
 
 			if err := db.Model(&record).Update("description", input.Description).Error; err != nil {
 					log.Printf("Failed to update description for p_id: %d, error: %v", id, err)
-					http.Error(w, "Failed to update description", http.StatusInternalServerError)
+					apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to update description", err))
 					return
 			}
 
@@ -168,21 +227,22 @@ func UpdateDescriptionByPID(db *gorm.DB) http.HandlerFunc {
 
 func UpdatePrescription(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		type UpdateData struct {
 			IDs          []int  `json:"ids"`
 			Prescription string `json:"prescription"`
 		}
 		var data UpdateData
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			http.Error(w, "Invalid input", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInvalidJSON, "Invalid input", err))
 			return
 		}
 		if data.Prescription == "" {
-			http.Error(w, "Prescription cannot be empty", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "prescription", Code: "required", Message: "Prescription cannot be empty"}))
 			return
 		}
 		if err := db.Model(&models.Record{}).Where("id IN ?", data.IDs).Update("prescription", data.Prescription).Error; err != nil {
-			http.Error(w, "Failed to update prescription", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to update prescription", err))
 			log.Println("Prescription update error:", err)
 			return
 		}
@@ -192,14 +252,15 @@ func UpdatePrescription(db *gorm.DB) http.HandlerFunc {
 }
 func DeleteRecord(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		db := db.WithContext(r.Context())
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.New(apierror.CodeInvalidParameter, "Invalid ID"))
 			return
 		}
 		if err := db.Delete(&models.Record{}, id).Error; err != nil {
-			http.Error(w, "Failed to delete record", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to delete record", err))
 			log.Println("Record deletion error:", err)
 			return
 		}
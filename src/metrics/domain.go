@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+const gaugeQueryTimeout = 2 * time.Second
+
+func registerDomainGauges(db *gorm.DB) error {
+	admitted := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "admitted_patients",
+		Help:      "Patients currently in the admitted table.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), gaugeQueryTimeout)
+		defer cancel()
+
+		var count int64
+		if err := db.WithContext(ctx).Table("admitted").Count(&count).Error; err != nil {
+			log.Printf("Error counting admitted patients for metrics: %v", err)
+			return 0
+		}
+		return float64(count)
+	})
+
+	return registry.Register(admitted)
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+)
+
+// Middleware records request counts and latencies labelled with the mux
+// path template (e.g. /api/patients/{p_id}) rather than the raw URL, so that
+// label cardinality stays bounded.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		m := httpsnoop.CaptureMetrics(next, w, r)
+
+		HTTPRequests.WithLabelValues(route, r.Method, strconv.Itoa(m.Code)).Inc()
+		HTTPDuration.WithLabelValues(route, r.Method).Observe(m.Duration.Seconds())
+	})
+}
+
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "unmatched"
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return "unmatched"
+	}
+	return tpl
+}
@@ -2,43 +2,43 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/PragaL15/med_admin_backend/src/apierror"
 	"github.com/PragaL15/med_admin_backend/src/utils" 
 	"gorm.io/gorm"
 )
 
-const (
-	ErrUnauthorized   = "Unauthorized"
-	ErrInternalServer = "Internal Server Error"
-	ErrForbidden      = "Forbidden"
-	ErrNotAuthorized  = "Not Authorized"
-)
-
 func RoleBasedAccessMiddleware(db *gorm.DB) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userID, err := getUserIDFromJWT(r)
 			if err != nil {
-				http.Error(w, ErrUnauthorized, http.StatusUnauthorized)
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeUnauthorized, "Missing or invalid bearer token", err))
 				return
 			}
 			log.Printf("User ID from JWT: %d", userID)
 
 			routePath := r.URL.Path
+			db := db.WithContext(r.Context())
 
 			roleID, err := getUserRoleFromAPI(db, userID, routePath)
 			if err != nil {
 				log.Printf("Error getting user role from API for user_id: %d and route: %s - %v", userID, routePath, err)
-				http.Error(w, ErrInternalServer, http.StatusInternalServerError)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					apierror.Write(w, r, apierror.New(apierror.CodeForbidden, "No role grants access to this route"))
+					return
+				}
+				apierror.Write(w, r, apierror.Wrap(apierror.CodeInternal, "Failed to resolve user role", err))
 				return
 			}
 			if !hasPermission(db, roleID, routePath) {
-				http.Error(w, ErrNotAuthorized, http.StatusForbidden)
+				apierror.Write(w, r, apierror.New(apierror.CodeForbidden, "Not authorized for this route"))
 				return
 			}
 			ctx := context.WithValue(r.Context(), "userID", userID)
@@ -104,4 +104,10 @@ func hasPermission(db *gorm.DB, roleID int, routePath string) bool {
 	}
 
 	return permissionCount > 0
-}
\ No newline at end of file
+}
+
+// UserIDFromContext returns the user_id stored by RoleBasedAccessMiddleware.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value("userID").(int)
+	return userID, ok
+}
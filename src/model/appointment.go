@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Appointment statuses. The lifecycle is requested → confirmed → checked-in
+// → in-consultation → completed; cancelled and no-show end it early.
+const (
+	StatusRequested      = "requested"
+	StatusConfirmed      = "confirmed"
+	StatusCheckedIn      = "checked-in"
+	StatusInConsultation = "in-consultation"
+	StatusCompleted      = "completed"
+	StatusCancelled      = "cancelled"
+	StatusNoShow         = "no-show"
+)
+
+// Kinds of AppointmentChange.
+const (
+	AppointmentCreated       = "created"
+	AppointmentUpdated       = "updated"
+	AppointmentRescheduled   = "rescheduled"
+	AppointmentCancelled     = "cancelled"
+	AppointmentStatusChanged = "status_changed"
+	AppointmentDeleted       = "deleted"
+)
+
+// AppointmentChange is one entry in an appointment's history. From and To
+// fields are only set for what the change touched. Fields holds edits to
+// the notes as {"field": {"from": ..., "to": ...}}.
+type AppointmentChange struct {
+	ID            int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	AppointmentID int        `gorm:"column:appointment_id;not null;index" json:"appointment_id"`
+	Kind          string     `gorm:"column:kind;not null" json:"kind"`
+	FromStart     *time.Time `gorm:"column:from_start" json:"from_start,omitempty"`
+	ToStart       *time.Time `gorm:"column:to_start" json:"to_start,omitempty"`
+	FromDID       *int       `gorm:"column:from_d_id" json:"from_d_id,omitempty"`
+	ToDID         *int       `gorm:"column:to_d_id" json:"to_d_id,omitempty"`
+	FromStatus    string     `gorm:"column:from_status" json:"from_status,omitempty"`
+	ToStatus      string     `gorm:"column:to_status" json:"to_status,omitempty"`
+	Fields        *string    `gorm:"column:fields;type:jsonb" json:"fields,omitempty"`
+	Reason        string     `gorm:"column:reason" json:"reason,omitempty"`
+	ChangedBy     int        `gorm:"column:changed_by" json:"changed_by"`
+	ChangedAt     time.Time  `gorm:"column:changed_at;not null" json:"changed_at"`
+}
+
+func (AppointmentChange) TableName() string {
+	return "appointment_changes"
+}
+
+// AppointmentSeries is a recurring booking. Its occurrences are ordinary
+// appointments carrying SeriesID, so each one can be rescheduled or
+// cancelled on its own.
+type AppointmentSeries struct {
+	ID              int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID             int        `gorm:"column:p_id;not null;index" json:"p_id"`
+	DID             int        `gorm:"column:d_id;not null;index" json:"d_id"`
+	RRule           string     `gorm:"column:rrule;not null" json:"rrule"`
+	FirstStart      time.Time  `gorm:"column:first_start;not null" json:"first_start"`
+	DurationMinutes int        `gorm:"column:duration_minutes;not null" json:"duration_minutes"`
+	PHealth         string     `gorm:"column:p_health" json:"p_health"`
+	ProblemHint     string     `gorm:"column:problem_hint" json:"problem_hint"`
+	CancelledFrom   *time.Time `gorm:"column:cancelled_from" json:"cancelled_from,omitempty"`
+	CancelReason    string     `gorm:"column:cancel_reason" json:"cancel_reason,omitempty"`
+	CreatedBy       int        `gorm:"column:created_by" json:"created_by"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (AppointmentSeries) TableName() string {
+	return "appointment_series"
+}
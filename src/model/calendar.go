@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Calendar feed owners.
+const (
+	CalendarDoctor  = "doctor"
+	CalendarPatient = "patient"
+)
+
+// CalendarToken authenticates one calendar subscription feed. Only a hash
+// of the token is stored; the token itself is shown once, when issued.
+// OwnerID is the d_id or p_id.
+type CalendarToken struct {
+	ID         int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Owner      string     `gorm:"column:owner;not null;index:calendar_tokens_owner_idx,priority:1" json:"owner"`
+	OwnerID    int        `gorm:"column:owner_id;not null;index:calendar_tokens_owner_idx,priority:2" json:"owner_id"`
+	TokenHash  string     `gorm:"column:token_hash;not null;uniqueIndex" json:"-"`
+	Label      string     `gorm:"column:label" json:"label,omitempty" validate:"max=100"`
+	CreatedBy  int        `gorm:"column:created_by" json:"created_by"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at,omitempty"`
+	RevokedBy  *int       `gorm:"column:revoked_by" json:"revoked_by,omitempty"`
+}
+
+func (CalendarToken) TableName() string {
+	return "calendar_tokens"
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Coding systems accepted alongside a clinical code.
+var CodeSystems = []string{"icd10", "snomed", "rxnorm", "local"}
+
+var (
+	AllergyCategories = []string{"drug", "food", "environment", "other"}
+	AllergySeverities = []string{"mild", "moderate", "severe", "life_threatening"}
+	AllergyStatuses   = []string{"active", "inactive", "resolved", "entered_in_error"}
+	ProblemStatuses   = []string{"active", "inactive", "resolved", "entered_in_error"}
+)
+
+// PatientAllergy is an allergy or intolerance. Entries are never deleted;
+// mistakes are marked entered_in_error so the history stays intact.
+type PatientAllergy struct {
+	ID         int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID        uint       `gorm:"column:p_id;not null;index" json:"p_id"`
+	Substance  string     `gorm:"column:substance;not null" json:"substance" validate:"required,max=100"`
+	Code       string     `gorm:"column:code" json:"code,omitempty" validate:"max=50"`
+	CodeSystem string     `gorm:"column:code_system" json:"code_system,omitempty" validate:"required_with=Code,omitempty,code_system"`
+	Category   string     `gorm:"column:category;not null;default:other" json:"category" validate:"omitempty,allergy_category"`
+	Reaction   string     `gorm:"column:reaction" json:"reaction" validate:"max=255"`
+	Severity   string     `gorm:"column:severity;not null" json:"severity" validate:"required,allergy_severity"`
+	Status     string     `gorm:"column:status;not null;default:active" json:"status" validate:"omitempty,allergy_status"`
+	OnsetDate  *time.Time `gorm:"column:onset_date;type:date" json:"onset_date,omitempty" validate:"omitempty,notfuture"`
+	Notes      string     `gorm:"column:notes" json:"notes" validate:"max=500"`
+	RecordedBy int        `gorm:"column:recorded_by" json:"recorded_by"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (PatientAllergy) TableName() string {
+	return "patient_allergies"
+}
+
+// PatientProblem is an entry on the patient's problem list: a diagnosis or
+// chronic condition with the period it was present.
+type PatientProblem struct {
+	ID           int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID          uint       `gorm:"column:p_id;not null;index" json:"p_id"`
+	Description  string     `gorm:"column:description;not null" json:"description" validate:"required,max=255"`
+	Code         string     `gorm:"column:code" json:"code,omitempty" validate:"max=50"`
+	CodeSystem   string     `gorm:"column:code_system" json:"code_system,omitempty" validate:"required_with=Code,omitempty,code_system"`
+	Status       string     `gorm:"column:status;not null;default:active" json:"status" validate:"omitempty,problem_status"`
+	Chronic      bool       `gorm:"column:chronic;not null;default:false" json:"chronic"`
+	OnsetDate    *time.Time `gorm:"column:onset_date;type:date" json:"onset_date,omitempty" validate:"omitempty,notfuture"`
+	ResolvedDate *time.Time `gorm:"column:resolved_date;type:date" json:"resolved_date,omitempty" validate:"omitempty,notfuture"`
+	Notes        string     `gorm:"column:notes" json:"notes" validate:"max=500"`
+	RecordedBy   int        `gorm:"column:recorded_by" json:"recorded_by"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (PatientProblem) TableName() string {
+	return "patient_problems"
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Consent types recorded for a patient.
+const (
+	ConsentTreatment     = "treatment"
+	ConsentDataSharing   = "data_sharing"
+	ConsentCommunication = "communication"
+	ConsentResearch      = "research"
+)
+
+var ConsentTypes = []string{ConsentTreatment, ConsentDataSharing, ConsentCommunication, ConsentResearch}
+
+// Consent statuses. Refused is a decision recorded at the time of asking;
+// withdrawn is a previously granted consent that was taken back.
+const (
+	ConsentGranted   = "granted"
+	ConsentRefused   = "refused"
+	ConsentWithdrawn = "withdrawn"
+)
+
+var ConsentStatuses = []string{ConsentGranted, ConsentRefused, ConsentWithdrawn}
+
+// DefaultConsentScope applies when a consent is not limited to a purpose.
+const DefaultConsentScope = "general"
+
+// PatientConsent is one consent decision. Rows are kept as a ledger: the
+// latest row for a type and scope is the patient's current decision, and a
+// withdrawal updates the granted row rather than deleting it.
+type PatientConsent struct {
+	ID               int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID              uint       `gorm:"column:p_id;not null;index" json:"p_id"`
+	Type             string     `gorm:"column:type;not null" json:"type" validate:"required,consent_type"`
+	Scope            string     `gorm:"column:scope;not null;default:general" json:"scope" validate:"omitempty,max=100"`
+	Status           string     `gorm:"column:status;not null;default:granted" json:"status" validate:"omitempty,consent_status"`
+	GivenAt          time.Time  `gorm:"column:given_at;not null" json:"given_at" validate:"notfuture"`
+	ExpiresAt        *time.Time `gorm:"column:expires_at" json:"expires_at,omitempty"`
+	Witness          string     `gorm:"column:witness" json:"witness,omitempty" validate:"max=100"`
+	DocumentRef      string     `gorm:"column:document_ref" json:"document_ref,omitempty" validate:"max=255"`
+	WithdrawnAt      *time.Time `gorm:"column:withdrawn_at" json:"withdrawn_at,omitempty"`
+	WithdrawnBy      *int       `gorm:"column:withdrawn_by" json:"withdrawn_by,omitempty"`
+	WithdrawalReason string     `gorm:"column:withdrawal_reason" json:"withdrawal_reason,omitempty"`
+	RecordedBy       int        `gorm:"column:recorded_by" json:"recorded_by"`
+	RecordedAt       time.Time  `gorm:"column:recorded_at;not null" json:"recorded_at"`
+}
+
+func (PatientConsent) TableName() string {
+	return "patient_consents"
+}
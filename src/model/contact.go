@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Relationship types accepted for PatientContact.Relationship, read as "the
+// contact is the patient's ...".
+var ContactRelationships = []string{
+	"spouse", "partner", "parent", "child", "sibling", "grandparent", "grandchild",
+	"guardian", "relative", "friend", "caregiver", "other",
+}
+
+// PatientContact is a person connected to a patient: next of kin, a legal
+// guardian, an emergency contact or a relative. When the person is also a
+// patient, LinkedPID points at their record.
+type PatientContact struct {
+	ID                int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID               uint      `gorm:"column:p_id;not null;index" json:"p_id"`
+	LinkedPID         *uint     `gorm:"column:linked_p_id;index" json:"linked_p_id,omitempty"`
+	Name              string    `gorm:"column:name;not null" json:"name" validate:"required,max=100"`
+	Relationship      string    `gorm:"column:relationship;not null" json:"relationship" validate:"required,contact_relationship"`
+	Phone             string    `gorm:"column:phone;not null" json:"phone" validate:"required,e164"`
+	Email             string    `gorm:"column:email" json:"email" validate:"omitempty,email"`
+	Address           string    `gorm:"column:address" json:"address" validate:"max=255"`
+	IsEmergency       bool      `gorm:"column:is_emergency;not null;default:false" json:"is_emergency"`
+	IsNextOfKin       bool      `gorm:"column:is_next_of_kin;not null;default:false" json:"is_next_of_kin"`
+	IsLegalGuardian   bool      `gorm:"column:is_legal_guardian;not null;default:false" json:"is_legal_guardian"`
+	MayReceiveResults bool      `gorm:"column:may_receive_results;not null;default:false" json:"may_receive_results"`
+	MayConsent        bool      `gorm:"column:may_consent;not null;default:false" json:"may_consent"`
+	Notes             string    `gorm:"column:notes" json:"notes" validate:"max=500"`
+	CreatedAt         time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (PatientContact) TableName() string {
+	return "patient_contacts"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+var DocumentCategories = []string{"lab_result", "imaging", "referral", "discharge_summary", "consent_form", "identity", "insurance", "other"}
+
+// Document is the metadata of an uploaded file. The bytes live in the
+// configured storage under StorageKey; ContentType is sniffed from the
+// content, not taken from the client.
+type Document struct {
+	ID          int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID         uint      `gorm:"column:p_id;not null;index" json:"p_id"`
+	RecordID    *int      `gorm:"column:record_id;index" json:"record_id,omitempty"`
+	Category    string    `gorm:"column:category;not null;default:other" json:"category" validate:"omitempty,document_category"`
+	FileName    string    `gorm:"column:file_name;not null" json:"file_name" validate:"required,max=255"`
+	Description string    `gorm:"column:description" json:"description" validate:"max=500"`
+	ContentType string    `gorm:"column:content_type;not null" json:"content_type"`
+	Size        int64     `gorm:"column:size;not null" json:"size"`
+	SHA256      string    `gorm:"column:sha256;not null;index" json:"sha256"`
+	StorageKey  string    `gorm:"column:storage_key;not null;uniqueIndex" json:"-"`
+	UploadedBy  int       `gorm:"column:uploaded_by" json:"uploaded_by"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (Document) TableName() string {
+	return "documents"
+}
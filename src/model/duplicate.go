@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	DuplicatePending   = "pending"
+	DuplicateDismissed = "dismissed"
+	DuplicateMerged    = "merged"
+)
+
+// DuplicateCandidate is a review queue entry pairing a newly created patient
+// with an existing one that probably is the same person.
+type DuplicateCandidate struct {
+	ID           int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID          uint       `gorm:"column:p_id;not null;uniqueIndex:duplicate_pair_idx" json:"p_id"`
+	CandidatePID uint       `gorm:"column:candidate_p_id;not null;uniqueIndex:duplicate_pair_idx" json:"candidate_p_id"`
+	Score        float64    `gorm:"column:score;not null" json:"score"`
+	Reasons      string     `gorm:"column:reasons" json:"reasons"`
+	Status       string     `gorm:"column:status;not null;default:pending;index" json:"status"`
+	ReviewedBy   *int       `gorm:"column:reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `gorm:"column:reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (DuplicateCandidate) TableName() string {
+	return "patient_duplicate_candidates"
+}
+
+// PatientMerge is the audit trail of a merge: which rows were re-pointed and
+// a snapshot of the retired patient row.
+type PatientMerge struct {
+	ID          int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SurvivorPID uint      `gorm:"column:survivor_p_id;not null;index" json:"survivor_p_id"`
+	MergedPID   uint      `gorm:"column:merged_p_id;not null;index" json:"merged_p_id"`
+	MergedBy    int       `gorm:"column:merged_by" json:"merged_by"`
+	Moved       string    `gorm:"column:moved;type:jsonb" json:"moved"`
+	Snapshot    string    `gorm:"column:snapshot;type:jsonb" json:"snapshot"`
+	MergedAt    time.Time `gorm:"column:merged_at;autoCreateTime" json:"merged_at"`
+}
+
+func (PatientMerge) TableName() string {
+	return "patient_merges"
+}
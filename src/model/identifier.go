@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Identifier types accepted for PatientIdentifier.Type.
+var IdentifierTypes = []string{"national_id", "insurance_member_id", "legacy_id", "passport", "other"}
+
+// PatientIdentifier is an identifier assigned to a patient by an outside
+// issuer (a government, an insurer, a previous system). A value is unique per
+// issuer, not globally.
+type PatientIdentifier struct {
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID       uint      `gorm:"column:p_id;not null;index" json:"p_id"`
+	Type      string    `gorm:"column:type;not null" json:"type" validate:"required,identifier_type"`
+	Issuer    string    `gorm:"column:issuer;not null;uniqueIndex:identifier_issuer_value_idx" json:"issuer" validate:"required,max=100"`
+	Value     string    `gorm:"column:value;not null;uniqueIndex:identifier_issuer_value_idx" json:"value" validate:"required,max=100"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (PatientIdentifier) TableName() string {
+	return "patient_identifiers"
+}
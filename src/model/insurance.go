@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// PatientInsurance is a coverage record. The member ID is also stored as a
+// PatientIdentifier issued by the payer, which keeps it unique per payer.
+type PatientInsurance struct {
+	ID          int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID         uint       `gorm:"column:p_id;not null;index" json:"p_id"`
+	Payer       string     `gorm:"column:payer;not null" json:"payer" validate:"required,max=100"`
+	MemberID    string     `gorm:"column:member_id;not null" json:"member_id" validate:"required,max=100"`
+	GroupNumber string     `gorm:"column:group_number" json:"group_number" validate:"max=100"`
+	PlanName    string     `gorm:"column:plan_name" json:"plan_name" validate:"max=100"`
+	ValidFrom   *time.Time `gorm:"column:valid_from;type:date" json:"valid_from,omitempty"`
+	ValidTo     *time.Time `gorm:"column:valid_to;type:date" json:"valid_to,omitempty" validate:"omitempty,gtfield=ValidFrom"`
+	IsPrimary   bool       `gorm:"column:is_primary;not null;default:false" json:"is_primary"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (PatientInsurance) TableName() string {
+	return "patient_insurance"
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Notification kinds.
+const (
+	NotificationReminder      = "appointment_reminder"
+	NotificationConfirmed     = "appointment_confirmed"
+	NotificationRescheduled   = "appointment_rescheduled"
+	NotificationCancelled     = "appointment_cancelled"
+	NotificationWaitlistOffer = "waitlist_offer"
+)
+
+var NotificationKinds = []string{NotificationReminder, NotificationConfirmed, NotificationRescheduled, NotificationCancelled, NotificationWaitlistOffer}
+
+// Notification statuses. Pending rows are being sent; skipped ones were
+// never sent, for lack of consent or of a recipient address.
+const (
+	NotificationPending   = "pending"
+	NotificationSent      = "sent"
+	NotificationDelivered = "delivered"
+	NotificationFailed    = "failed"
+	NotificationSkipped   = "skipped"
+)
+
+var NotificationStatuses = []string{NotificationPending, NotificationSent, NotificationDelivered, NotificationFailed, NotificationSkipped}
+
+// Notification is one message to a patient over one channel, kept to track
+// delivery. Appointment messages are unique per appointment, kind, channel
+// and DueAt: a reminder is sent once per start time and again after a
+// reschedule, and a change notice once per change.
+type Notification struct {
+	ID            int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID           int        `gorm:"column:p_id;not null;index" json:"p_id"`
+	AppointmentID *int       `gorm:"column:appointment_id;uniqueIndex:notifications_reminder_idx,priority:1" json:"appointment_id,omitempty"`
+	OfferID       *int       `gorm:"column:offer_id" json:"offer_id,omitempty"`
+	Kind          string     `gorm:"column:kind;not null;uniqueIndex:notifications_reminder_idx,priority:2" json:"kind"`
+	Channel       string     `gorm:"column:channel;not null;uniqueIndex:notifications_reminder_idx,priority:3" json:"channel"`
+	DueAt         time.Time  `gorm:"column:due_at;not null;uniqueIndex:notifications_reminder_idx,priority:4" json:"due_at"`
+	Recipient     string     `gorm:"column:recipient" json:"recipient"`
+	Language      string     `gorm:"column:language" json:"language"`
+	Subject       string     `gorm:"column:subject" json:"subject,omitempty"`
+	Body          string     `gorm:"column:body" json:"body"`
+	Status        string     `gorm:"column:status;not null;default:pending;index" json:"status"`
+	Attempts      int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	Error         string     `gorm:"column:error" json:"error,omitempty"`
+	ProviderID    string     `gorm:"column:provider_id;index" json:"provider_id,omitempty"`
+	SentAt        *time.Time `gorm:"column:sent_at" json:"sent_at,omitempty"`
+	DeliveredAt   *time.Time `gorm:"column:delivered_at" json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+var LeaveKinds = []string{"leave", "holiday", "sick", "training"}
+
+// DoctorSchedule is one weekly working window. A doctor can have several
+// windows on the same weekday, for example a morning and an evening clinic.
+// Weekday follows time.Weekday: 0 is Sunday. StartTime and EndTime are
+// "HH:MM" wall clock times.
+type DoctorSchedule struct {
+	ID          int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	DID         int        `gorm:"column:d_id;not null;index" json:"d_id"`
+	Weekday     int        `gorm:"column:weekday;not null" json:"weekday" validate:"gte=0,lte=6"`
+	StartTime   string     `gorm:"column:start_time;not null" json:"start_time" validate:"required,datetime=15:04"`
+	EndTime     string     `gorm:"column:end_time;not null" json:"end_time" validate:"required,datetime=15:04"`
+	SlotMinutes int        `gorm:"column:slot_minutes;not null;default:15" json:"slot_minutes" validate:"omitempty,gte=5,lte=480"`
+	ValidFrom   *time.Time `gorm:"column:valid_from;type:date" json:"valid_from,omitempty"`
+	ValidTo     *time.Time `gorm:"column:valid_to;type:date" json:"valid_to,omitempty"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (DoctorSchedule) TableName() string {
+	return "doctor_schedules"
+}
+
+// DoctorScheduleOverride replaces the weekly windows on one date. All the
+// overrides of a date together make up that day; a Closed override makes the
+// doctor unavailable for the whole day.
+type DoctorScheduleOverride struct {
+	ID          int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	DID         int       `gorm:"column:d_id;not null;index:doctor_override_date_idx,priority:1" json:"d_id"`
+	Date        time.Time `gorm:"column:date;type:date;not null;index:doctor_override_date_idx,priority:2" json:"date" validate:"required"`
+	Closed      bool      `gorm:"column:closed;not null;default:false" json:"closed"`
+	StartTime   string    `gorm:"column:start_time" json:"start_time,omitempty" validate:"required_unless=Closed true,omitempty,datetime=15:04"`
+	EndTime     string    `gorm:"column:end_time" json:"end_time,omitempty" validate:"required_unless=Closed true,omitempty,datetime=15:04"`
+	SlotMinutes int       `gorm:"column:slot_minutes;not null;default:15" json:"slot_minutes" validate:"omitempty,gte=5,lte=480"`
+	Reason      string    `gorm:"column:reason" json:"reason,omitempty" validate:"max=255"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (DoctorScheduleOverride) TableName() string {
+	return "doctor_schedule_overrides"
+}
+
+// DoctorLeave blocks whole days from StartsOn to EndsOn, both inclusive.
+type DoctorLeave struct {
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	DID       int       `gorm:"column:d_id;not null;index" json:"d_id"`
+	StartsOn  time.Time `gorm:"column:starts_on;type:date;not null" json:"starts_on" validate:"required"`
+	EndsOn    time.Time `gorm:"column:ends_on;type:date;not null" json:"ends_on" validate:"required"`
+	Kind      string    `gorm:"column:kind;not null;default:leave" json:"kind" validate:"omitempty,leave_kind"`
+	Reason    string    `gorm:"column:reason" json:"reason,omitempty" validate:"max=255"`
+	CreatedBy int       `gorm:"column:created_by" json:"created_by"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (DoctorLeave) TableName() string {
+	return "doctor_leave"
+}
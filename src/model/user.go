@@ -4,15 +4,25 @@ import (
 	"time"
 )
 
+// Accepted values for the enum-like string columns. Comparisons are case
+// insensitive so existing capitalised rows stay valid.
+var (
+	PatientStatuses     = []string{"active", "admitted", "critical", "recovered", "discharged", "inactive", "deceased", "merged"}
+	Genders             = []string{"male", "female", "other", "unknown"}
+	PatientModes        = []string{"outpatient", "inpatient", "emergency", "online"}
+	DoctorStatuses      = []string{"active", "on_leave", "inactive"}
+	AppointmentStatuses = []string{StatusRequested, StatusConfirmed, StatusCheckedIn, StatusInConsultation, StatusCompleted, StatusCancelled, StatusNoShow}
+)
+
 type Record struct {
 	ID          int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	PID         int       `gorm:"column:p_id;not null" json:"p_id"`
-	DID         int       `gorm:"column:d_id;not null" json:"d_id"`
-	Date        time.Time `gorm:"column:date;not null" json:"date"`
+	PID         int       `gorm:"column:p_id;not null" json:"p_id" validate:"required,gt=0"`
+	DID         int       `gorm:"column:d_id;not null" json:"d_id" validate:"required,gt=0"`
+	Date        time.Time `gorm:"column:date;not null" json:"date" validate:"required"`
 	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
-	Description string    `gorm:"column:description" json:"description"`
-	Prescription string   `gorm:"column:prescription" json:"prescription"`
+	Description string    `gorm:"column:description" json:"description" validate:"max=5000"`
+	Prescription string   `gorm:"column:prescription" json:"prescription" validate:"max=5000"`
 }
 
 func (Record) TableName() string {
@@ -21,18 +31,20 @@ func (Record) TableName() string {
 
 type Patient struct {
 	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`               
-	PID       uint      `gorm:"column:p_id;autoIncrement;not null;uniqueIndex" json:"p_id"`     
-	Name      string    `gorm:"column:p_name;not null" json:"name"`               
-	Phone     string    `gorm:"column:p_number;not null" json:"number"`             
-	Email     string    `gorm:"column:p_email;not null" json:"email"`              
-	Status    string    `gorm:"column:p_status" json:"status"`                     
-	Address   string    `gorm:"column:p_address" json:"address"`                   
-	Mode      string    `gorm:"column:p_mode" json:"mode"`                        
-	Age       int       `gorm:"column:p_age;not null" json:"age"`                  
-	Gender    string    `gorm:"column:p_gender;not null" json:"gender"`
-	DOB       time.Time   `gorm:"type:date"column:dob;not null" json:"dob"`       
-	Occupation string `gorm:"column:occupation;not null" json:"occupation"`     
-	Language   string `gorm:"column:lang_spoken;not null" json:"lang_spoken"`
+	PID       uint      `gorm:"column:p_id;not null;uniqueIndex;default:(-)" json:"p_id"`
+	MRN       string    `gorm:"column:mrn;uniqueIndex" json:"mrn"`
+	Name      string    `gorm:"column:p_name;not null" json:"name" validate:"required,max=100"`
+	Phone     string    `gorm:"column:p_number;not null" json:"number" validate:"required,e164"`
+	Email     string    `gorm:"column:p_email;not null" json:"email" validate:"required,email"`
+	Status    string    `gorm:"column:p_status" json:"status" validate:"omitempty,patient_status"`
+	Address   string    `gorm:"column:p_address" json:"address" validate:"max=255"`
+	Mode      string    `gorm:"column:p_mode" json:"mode" validate:"omitempty,patient_mode"`
+	Age       int       `gorm:"column:p_age;not null" json:"age" validate:"gte=0,lte=150"`
+	Gender    string    `gorm:"column:p_gender;not null" json:"gender" validate:"required,gender"`
+	DOB       time.Time `gorm:"column:dob;type:date;not null" json:"dob" validate:"required,notfuture"`
+	Occupation string `gorm:"column:occupation;not null" json:"occupation" validate:"required,max=100"`
+	Language   string `gorm:"column:lang_spoken;not null" json:"lang_spoken" validate:"required,max=50"`
+	MergedInto *uint  `gorm:"column:merged_into_p_id" json:"merged_into_p_id,omitempty"`
 	CreatedAt time.Time `gorm:"column:createdat;autoCreateTime" json:"createdAt"` 
 	UpdatedAt time.Time `gorm:"column:updatedat;autoUpdateTime" json:"updatedAt"` 
 }
@@ -42,11 +54,11 @@ func (Patient) TableName() string {
 
 type Doctor struct {
 	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	DID       uint      `gorm:"column:d_id;not null;uniqueIndex" json:"d_id"`
-	DName     string    `gorm:"column:d_name" json:"d_name"`
-	DNumber   int64     `gorm:"column:d_number" json:"d_number"`
-	DEmail    string    `gorm:"column:d_email" json:"d_email"`
-	DStatus   string    `gorm:"column:d_status" json:"d_status"`
+	DID       uint      `gorm:"column:d_id;not null;uniqueIndex" json:"d_id" validate:"required"`
+	DName     string    `gorm:"column:d_name" json:"d_name" validate:"required,max=100"`
+	DNumber   int64     `gorm:"column:d_number" json:"d_number" validate:"omitempty,gte=1000000,lte=999999999999999"`
+	DEmail    string    `gorm:"column:d_email" json:"d_email" validate:"omitempty,email"`
+	DStatus   string    `gorm:"column:d_status" json:"d_status" validate:"omitempty,doctor_status"`
 	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 }
@@ -59,31 +71,44 @@ type DoctorWithPatients struct {
 	PIDs  []int  `json:"p_ids"`
 }
 type Appointment struct {
-	ID            int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	PID           int       `gorm:"column:p_id;not null" json:"p_id"`
-	PName         string    `gorm:"column:p_name" json:"p_name"`
-	PNumber       string    `gorm:"column:p_number" json:"p_number"`
-	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
-	AppDate       time.Time `gorm:"column:app_date;not null" json:"app_date"`
-	PHealth       string    `gorm:"column:p_health" json:"p_health"`
-	DID           int       `gorm:"column:d_id;not null" json:"d_id"`
-	Time          string    `gorm:"column:time" json:"time"`
-	ProblemHint   string    `gorm:"column:problem_hint" json:"problem_hint"`
-	AppoStatus    string    `gorm:"column:appo_status" json:"appo_status"`
+	ID              int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID             int        `gorm:"column:p_id;not null" json:"p_id"`
+	PName           string     `gorm:"column:p_name;->" json:"p_name"`
+	PNumber         string     `gorm:"column:p_number;->" json:"p_number"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+	StartsAt        time.Time  `gorm:"column:starts_at;not null" json:"starts_at"`
+	EndsAt          time.Time  `gorm:"column:ends_at;not null" json:"ends_at"`
+	PHealth         string     `gorm:"column:p_health" json:"p_health"`
+	DID             int        `gorm:"column:d_id;not null" json:"d_id"`
+	ProblemHint     string     `gorm:"column:problem_hint" json:"problem_hint"`
+	AppoStatus      string     `gorm:"column:appo_status" json:"appo_status"`
+	DurationMinutes int        `gorm:"column:duration_minutes;not null;default:15" json:"duration_minutes"`
+	CancelReason    string     `gorm:"column:cancel_reason" json:"cancel_reason,omitempty"`
+	CancelledAt     *time.Time `gorm:"column:cancelled_at" json:"cancelled_at,omitempty"`
+	CancelledBy     *int       `gorm:"column:cancelled_by" json:"cancelled_by,omitempty"`
+	StatusChangedAt *time.Time `gorm:"column:status_changed_at" json:"status_changed_at,omitempty"`
+	SeriesID        *int       `gorm:"column:series_id" json:"series_id,omitempty"`
+	WalkIn          bool       `gorm:"column:walk_in;not null;default:false" json:"walk_in"`
+	CheckedInAt     *time.Time `gorm:"column:checked_in_at" json:"checked_in_at,omitempty"`
+	QueueToken      *int       `gorm:"column:queue_token" json:"queue_token,omitempty"`
+	QueuePriority   *int       `gorm:"column:queue_priority" json:"queue_priority,omitempty"`
+	CalledAt        *time.Time `gorm:"column:called_at" json:"called_at,omitempty"`
 }
 func (Appointment) TableName() string {
 	return "appointments"
 }
 type AppointmentPost struct {
-	ID          int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	PID         int       `gorm:"column:p_id;not null" json:"p_id"`
-	AppDate     string    `gorm:"column:app_date;not null" json:"app_date"` 
-	PHealth     string    `gorm:"column:p_health" json:"p_health"`
-	DID         int       `gorm:"column:d_id;not null" json:"d_id"`
-	Time        string    `gorm:"column:time;not null" json:"time"`       
-	ProblemHint string    `gorm:"column:problem_hint" json:"problem_hint"`
-	AppoStatus  string    `gorm:"column:appo_status" json:"appo_status"`
+	ID              int    `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID             int    `gorm:"column:p_id;not null" json:"p_id" validate:"required,gt=0"`
+	StartsAt        string `gorm:"-" json:"starts_at" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	AppDate         string `gorm:"-" json:"app_date" validate:"required_without=StartsAt,omitempty,datetime=02-01-2006"`
+	PHealth         string `gorm:"column:p_health" json:"p_health" validate:"max=255"`
+	DID             int    `gorm:"column:d_id;not null" json:"d_id" validate:"required,gt=0"`
+	Time            string `gorm:"-" json:"time" validate:"required_without=StartsAt,omitempty,datetime=15:04:05"`
+	ProblemHint     string `gorm:"column:problem_hint" json:"problem_hint" validate:"max=255"`
+	AppoStatus      string `gorm:"column:appo_status" json:"appo_status" validate:"omitempty,appointment_status"`
+	DurationMinutes int    `gorm:"column:duration_minutes" json:"duration_minutes" validate:"omitempty,gte=5,lte=480"`
 }
 func (AppointmentPost) TableName() string {
 	return "appointments"
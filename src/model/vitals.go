@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ConsciousnessLevels is the ACVPU scale used by NEWS2.
+var ConsciousnessLevels = []string{"alert", "confusion", "voice", "pain", "unresponsive"}
+
+// NEWS2 clinical risk bands.
+const (
+	NEWS2RiskLow       = "low"
+	NEWS2RiskLowMedium = "low_medium"
+	NEWS2RiskMedium    = "medium"
+	NEWS2RiskHigh      = "high"
+)
+
+// VitalSign is one set of observations. Every measurement is optional; the
+// NEWS2 score is only computed when the full set needed for it is present.
+// SpO2Scale 2 is for patients with hypercapnic respiratory failure and must
+// be prescribed by a clinician.
+type VitalSign struct {
+	ID            int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID           uint      `gorm:"column:p_id;not null;index:vital_signs_patient_idx,priority:1" json:"p_id"`
+	AdmissionID   *int      `gorm:"column:admission_id;index" json:"admission_id,omitempty"`
+	RecordedAt    time.Time `gorm:"column:recorded_at;not null;index:vital_signs_patient_idx,priority:2" json:"recorded_at" validate:"notfuture"`
+	RespRate      *int      `gorm:"column:resp_rate" json:"resp_rate,omitempty" validate:"omitempty,gte=0,lte=80"`
+	SpO2          *int      `gorm:"column:spo2" json:"spo2,omitempty" validate:"omitempty,gte=0,lte=100"`
+	SpO2Scale     int       `gorm:"column:spo2_scale;not null;default:1" json:"spo2_scale" validate:"omitempty,oneof=1 2"`
+	OnOxygen      bool      `gorm:"column:on_oxygen;not null;default:false" json:"on_oxygen"`
+	SystolicBP    *int      `gorm:"column:systolic_bp" json:"systolic_bp,omitempty" validate:"omitempty,gte=0,lte=300"`
+	DiastolicBP   *int      `gorm:"column:diastolic_bp" json:"diastolic_bp,omitempty" validate:"omitempty,gte=0,lte=200"`
+	HeartRate     *int      `gorm:"column:heart_rate" json:"heart_rate,omitempty" validate:"omitempty,gte=0,lte=300"`
+	Temperature   *float64  `gorm:"column:temperature" json:"temperature,omitempty" validate:"omitempty,gte=25,lte=45"`
+	Consciousness string    `gorm:"column:consciousness" json:"consciousness,omitempty" validate:"omitempty,consciousness_level"`
+	NEWS2         *int      `gorm:"column:news2" json:"news2"`
+	NEWS2Risk     string    `gorm:"column:news2_risk" json:"news2_risk,omitempty"`
+	RecordedBy    int       `gorm:"column:recorded_by" json:"recorded_by"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (VitalSign) TableName() string {
+	return "vital_signs"
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Waitlist entry statuses.
+const (
+	WaitlistWaiting = "waiting"
+	WaitlistOffered = "offered"
+	WaitlistBooked  = "booked"
+	WaitlistRemoved = "removed"
+)
+
+var WaitlistStatuses = []string{WaitlistWaiting, WaitlistOffered, WaitlistBooked, WaitlistRemoved}
+
+// Slot offer statuses.
+const (
+	OfferPending  = "pending"
+	OfferAccepted = "accepted"
+	OfferDeclined = "declined"
+	OfferExpired  = "expired"
+)
+
+var OfferStatuses = []string{OfferPending, OfferAccepted, OfferDeclined, OfferExpired}
+
+// WaitlistEntry is a patient waiting for any slot with a doctor between
+// EarliestDate and LatestDate. Priority 1 is the most urgent; entries of the
+// same priority are served first come, first served.
+type WaitlistEntry struct {
+	ID              int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	PID             int       `gorm:"column:p_id;not null;index" json:"p_id" validate:"required,gt=0"`
+	DID             int       `gorm:"column:d_id;not null;index:waitlist_doctor_idx,priority:1" json:"d_id" validate:"required,gt=0"`
+	EarliestDate    time.Time `gorm:"column:earliest_date;type:date;not null" json:"earliest_date" validate:"required"`
+	LatestDate      time.Time `gorm:"column:latest_date;type:date;not null" json:"latest_date" validate:"required"`
+	Priority        int       `gorm:"column:priority;not null;default:3" json:"priority" validate:"omitempty,gte=1,lte=5"`
+	DurationMinutes int       `gorm:"column:duration_minutes;not null;default:15" json:"duration_minutes" validate:"omitempty,gte=5,lte=480"`
+	Status          string    `gorm:"column:status;not null;default:waiting;index:waitlist_doctor_idx,priority:2" json:"status"`
+	Notes           string    `gorm:"column:notes" json:"notes,omitempty" validate:"max=500"`
+	CreatedBy       int       `gorm:"column:created_by" json:"created_by"`
+	CreatedAt       time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (WaitlistEntry) TableName() string {
+	return "waitlist_entries"
+}
+
+// WaitlistOffer holds a freed slot for one waitlisted patient until
+// ExpiresAt. While pending, the slot cannot be booked by anyone else.
+type WaitlistOffer struct {
+	ID                  int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	EntryID             int        `gorm:"column:entry_id;not null;index" json:"entry_id"`
+	PID                 int        `gorm:"column:p_id;not null" json:"p_id"`
+	DID                 int        `gorm:"column:d_id;not null;index:waitlist_offer_hold_idx,priority:1" json:"d_id"`
+	StartsAt            time.Time  `gorm:"column:starts_at;not null" json:"starts_at"`
+	DurationMinutes     int        `gorm:"column:duration_minutes;not null" json:"duration_minutes"`
+	Status              string     `gorm:"column:status;not null;default:pending;index:waitlist_offer_hold_idx,priority:2" json:"status"`
+	ExpiresAt           time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	SourceAppointmentID *int       `gorm:"column:source_appointment_id" json:"source_appointment_id,omitempty"`
+	AppointmentID       *int       `gorm:"column:appointment_id" json:"appointment_id,omitempty"`
+	RespondedAt         *time.Time `gorm:"column:responded_at" json:"responded_at,omitempty"`
+	RespondedBy         *int       `gorm:"column:responded_by" json:"responded_by,omitempty"`
+	CreatedAt           time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (WaitlistOffer) TableName() string {
+	return "waitlist_offers"
+}
@@ -0,0 +1,83 @@
+// Package mrn renders medical record numbers. It depends on nothing else in
+// the backend, so the patients service and the database migrations can both
+// use it.
+package mrn
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFormat yields e.g. MRN-2026-0004211-5. Supported placeholders:
+// {YYYY} and {YY} for the registration year and {SEQ:n} for the sequence
+// value zero padded to n digits. A Luhn check digit over all digits of the
+// rendered value is appended unless MRN_CHECK_DIGIT=none.
+const DefaultFormat = "MRN-{YYYY}-{SEQ:7}"
+
+// SequenceSQL draws the next value for a new MRN.
+const SequenceSQL = "SELECT nextval('patient_mrn_seq')"
+
+var (
+	seqPlaceholder = regexp.MustCompile(`\{SEQ(?::(\d+))?\}`)
+	nonDigits      = regexp.MustCompile(`\D`)
+)
+
+func format() string {
+	if f := os.Getenv("MRN_FORMAT"); f != "" {
+		return f
+	}
+	return DefaultFormat
+}
+
+func checkDigitEnabled() bool {
+	return !strings.EqualFold(os.Getenv("MRN_CHECK_DIGIT"), "none")
+}
+
+// Render renders seq with the configured MRN_FORMAT and MRN_CHECK_DIGIT.
+func Render(seq int64, at time.Time) (string, error) {
+	return Format(format(), seq, at, checkDigitEnabled())
+}
+
+func Format(format string, seq int64, at time.Time, checkDigit bool) (string, error) {
+	if !seqPlaceholder.MatchString(format) {
+		return "", fmt.Errorf("MRN format %q has no {SEQ} placeholder", format)
+	}
+
+	mrn := strings.NewReplacer(
+		"{YYYY}", at.Format("2006"),
+		"{YY}", at.Format("06"),
+	).Replace(format)
+	mrn = seqPlaceholder.ReplaceAllStringFunc(mrn, func(m string) string {
+		width := 0
+		if sub := seqPlaceholder.FindStringSubmatch(m); sub[1] != "" {
+			width, _ = strconv.Atoi(sub[1])
+		}
+		return fmt.Sprintf("%0*d", width, seq)
+	})
+
+	if checkDigit {
+		mrn += "-" + strconv.Itoa(luhnCheckDigit(nonDigits.ReplaceAllString(mrn, "")))
+	}
+	return mrn, nil
+}
+
+func luhnCheckDigit(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
@@ -0,0 +1,51 @@
+package mrn
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	at := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		format     string
+		seq        int64
+		checkDigit bool
+		want       string
+		wantErr    bool
+	}{
+		{format: DefaultFormat, seq: 4211, want: "MRN-2026-0004211"},
+		{format: DefaultFormat, seq: 4211, checkDigit: true, want: "MRN-2026-0004211-5"},
+		{format: "P{YY}{SEQ}", seq: 42, want: "P2642"},
+		{format: "{SEQ:3}", seq: 12345, want: "12345"},
+		{format: "MRN-{YYYY}", seq: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Format(tt.format, tt.seq, at, tt.checkDigit)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Format(%q) = %q, want an error", tt.format, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Format(%q, %d) = %q, %v, want %q", tt.format, tt.seq, got, err, tt.want)
+		}
+	}
+}
+
+func TestLuhnCheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   int
+	}{
+		{"7992739871", 3},
+		{"0", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := luhnCheckDigit(tt.digits); got != tt.want {
+			t.Errorf("luhnCheckDigit(%q) = %d, want %d", tt.digits, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMSGateway posts messages to an HTTP SMS gateway as
+// {"from", "to", "body", "reference"} with a bearer API key. The gateway is
+// expected to answer with the message's {"id"}.
+type SMSGateway struct {
+	URL    string
+	APIKey string
+	From   string
+}
+
+func (g *SMSGateway) Send(ctx context.Context, m Message) (string, error) {
+	header := http.Header{}
+	if g.APIKey != "" {
+		header.Set("Authorization", "Bearer "+g.APIKey)
+	}
+	return postJSON(ctx, g.URL, map[string]string{
+		"from":      g.From,
+		"to":        m.To,
+		"body":      m.Body,
+		"reference": m.Reference,
+	}, header)
+}
+
+// WebhookSender posts the message to a messaging bridge (WhatsApp and the
+// like). With a Secret, the body is signed in X-Signature as the hex
+// HMAC-SHA256 of the request body.
+type WebhookSender struct {
+	URL    string
+	Secret string
+}
+
+func (s *WebhookSender) Send(ctx context.Context, m Message) (string, error) {
+	header := http.Header{}
+	if s.Secret != "" {
+		body, err := json.Marshal(m)
+		if err != nil {
+			return "", err
+		}
+		mac := hmac.New(sha256.New, []byte(s.Secret))
+		mac.Write(body)
+		header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+	return postJSON(ctx, s.URL, m, header)
+}
+
+// SMTP sends plain text email. Username and Password are optional; without
+// them the server must accept unauthenticated mail from this host.
+type SMTP struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+// Send returns the Message-ID it set, since SMTP has no other message ID.
+func (s *SMTP) Send(ctx context.Context, m Message) (string, error) {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid SMTP_ADDR %q: %v", s.Addr, err)
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	id := fmt.Sprintf("<%s.%d@%s>", m.Reference, time.Now().UnixNano(), host)
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Message-ID: %s\r\n", id)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	writeBody(&b, m)
+
+	if err := smtp.SendMail(s.Addr, auth, s.From, []string{m.To}, []byte(b.String())); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// writeBody writes the plain text body, as a multipart/mixed message when
+// there are attachments.
+func writeBody(b *strings.Builder, m Message) {
+	text := strings.ReplaceAll(m.Body, "\n", "\r\n")
+	if len(m.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		b.WriteString(text)
+		return
+	}
+
+	mw := multipart.NewWriter(b)
+	fmt.Fprintf(b, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mw.Boundary())
+	part, _ := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	part.Write([]byte(text))
+	for _, a := range m.Attachments {
+		part, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		})
+		enc := base64.StdEncoding.EncodeToString(a.Data)
+		for len(enc) > 76 {
+			part.Write([]byte(enc[:76] + "\r\n"))
+			enc = enc[76:]
+		}
+		part.Write([]byte(enc + "\r\n"))
+	}
+	mw.Close()
+}
+
+// Log appends each message as a JSON line to a file, standing in for every
+// channel.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewLog(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("error creating notification log directory: %v", err)
+	}
+	return &Log{path: path}, nil
+}
+
+func (l *Log) Send(ctx context.Context, m Message) (string, error) {
+	line, err := json.Marshal(struct {
+		SentAt time.Time `json:"sent_at"`
+		Message
+	}{time.Now(), m})
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return "", err
+	}
+	return "log-" + m.Reference, f.Close()
+}
@@ -0,0 +1,139 @@
+// Package notify sends messages to patients. Each delivery channel (SMS,
+// email, a WhatsApp-style webhook) is a Channel; the Log stand-in writes
+// messages to a file instead, for development and installs without
+// providers.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Channel names.
+const (
+	SMS     = "sms"
+	Email   = "email"
+	Webhook = "webhook"
+)
+
+var ChannelNames = []string{SMS, Email, Webhook}
+
+// ErrNotConfigured is returned for a channel without a provider.
+var ErrNotConfigured = errors.New("notification channel is not configured")
+
+// Message is one message to one recipient: a phone number for SMS and the
+// webhook, an address for email. Reference is our ID for the message, passed
+// to providers so delivery callbacks can name it. Attachments go out by email
+// and the webhook; SMS drops them.
+type Message struct {
+	Channel     string       `json:"channel"`
+	To          string       `json:"to"`
+	Subject     string       `json:"subject,omitempty"`
+	Body        string       `json:"body"`
+	Reference   string       `json:"reference"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment is a file sent with a message. Data is base64 in JSON.
+type Attachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Channel hands a message to a provider and returns the provider's ID for
+// it, which later delivery reports refer to.
+type Channel interface {
+	Send(ctx context.Context, m Message) (string, error)
+}
+
+// Channels maps channel names to their Channel.
+type Channels map[string]Channel
+
+// Send sends m over its channel.
+func (c Channels) Send(ctx context.Context, m Message) (string, error) {
+	ch, ok := c[m.Channel]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotConfigured, m.Channel)
+	}
+	return ch.Send(ctx, m)
+}
+
+// New builds the channels selected by NOTIFICATIONS: "log" (the default)
+// writes every channel to NOTIFICATIONS_LOG_FILE, "live" sends through the
+// providers configured in SMS_GATEWAY_*, SMTP_* and WHATSAPP_WEBHOOK_*.
+// Channels without provider settings are left out.
+func New() (Channels, error) {
+	switch kind := strings.ToLower(os.Getenv("NOTIFICATIONS")); kind {
+	case "", "log":
+		path := os.Getenv("NOTIFICATIONS_LOG_FILE")
+		if path == "" {
+			path = "data/notifications.log"
+		}
+		l, err := NewLog(path)
+		if err != nil {
+			return nil, err
+		}
+		return Channels{SMS: l, Email: l, Webhook: l}, nil
+	case "live":
+		channels := Channels{}
+		if url := os.Getenv("SMS_GATEWAY_URL"); url != "" {
+			channels[SMS] = &SMSGateway{URL: url, APIKey: os.Getenv("SMS_GATEWAY_API_KEY"), From: os.Getenv("SMS_GATEWAY_FROM")}
+		}
+		if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+			from := os.Getenv("SMTP_FROM")
+			if from == "" {
+				return nil, errors.New("SMTP_FROM is required when SMTP_ADDR is set")
+			}
+			channels[Email] = &SMTP{Addr: addr, From: from, Username: os.Getenv("SMTP_USERNAME"), Password: os.Getenv("SMTP_PASSWORD")}
+		}
+		if url := os.Getenv("WHATSAPP_WEBHOOK_URL"); url != "" {
+			channels[Webhook] = &WebhookSender{URL: url, Secret: os.Getenv("WHATSAPP_WEBHOOK_SECRET")}
+		}
+		return channels, nil
+	default:
+		return nil, fmt.Errorf("unknown NOTIFICATIONS %q", kind)
+	}
+}
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// postJSON posts payload to url and returns the "id" of a JSON response, if
+// any. Non-2xx responses are errors.
+func postJSON(ctx context.Context, url string, payload interface{}, header http.Header) (string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("provider returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var reply struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(data, &reply)
+	return reply.ID, nil
+}
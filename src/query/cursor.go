@@ -0,0 +1,71 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// cursor is the opaque keyset position handed to clients as next_cursor.
+// Values are stored as strings and compared by Postgres against the typed
+// sort columns.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Finish trims the extra row fetched by Page from rows (a pointer to a slice)
+// and builds the response metadata, including next_cursor when another page
+// exists.
+func (p *Params) Finish(rows interface{}, total int64) Meta {
+	meta := Meta{Total: total, Limit: p.Limit, Offset: p.Offset, Sort: p.sortString()}
+
+	v := reflect.ValueOf(rows).Elem()
+	if v.Len() <= p.Limit {
+		return meta
+	}
+	v.Set(v.Slice(0, p.Limit))
+	meta.HasMore = true
+
+	last := v.Index(p.Limit - 1)
+	if last.Kind() == reflect.Ptr {
+		last = last.Elem()
+	}
+	c := cursor{Sort: meta.Sort}
+	for _, o := range p.orders {
+		field := last.FieldByName(p.spec.Sorts[o.key].Field)
+		if !field.IsValid() {
+			// Misconfigured spec; fall back to offset paging for this response.
+			return meta
+		}
+		c.Values = append(c.Values, cursorValue(field.Interface()))
+	}
+	meta.NextCursor = c.encode()
+	return meta
+}
+
+func cursorValue(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,342 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// FilterKind says how a whitelisted query parameter is turned into a WHERE
+// clause.
+type FilterKind int
+
+const (
+	// Equals matches a column against one value, or several comma separated
+	// values (?status=admitted,critical).
+	Equals FilterKind = iota
+	// DateRange reads <param>_from and <param>_to (YYYY-MM-DD or RFC 3339),
+	// both inclusive, and ignores the bare parameter.
+	DateRange
+)
+
+// ValueType is the type Equals values must have. Values of the wrong type
+// are rejected by Parse instead of reaching the database.
+type ValueType int
+
+const (
+	Text ValueType = iota
+	// Int accepts values that fit a Postgres integer column.
+	Int
+)
+
+type Filter struct {
+	Column string
+	Kind   FilterKind
+	Type   ValueType
+}
+
+// Sort maps a public sort key to a column and to the Go struct field holding
+// that column's value in the result rows, which is needed to build cursors.
+type Sort struct {
+	Column string
+	Field  string
+}
+
+// Spec is the per-endpoint whitelist. Key is the unique tie breaker that is
+// always appended to the ordering so pages are stable; it must also be listed
+// in Sorts.
+type Spec struct {
+	Filters     map[string]Filter
+	Sorts       map[string]Sort
+	DefaultSort string
+	Key         string
+}
+
+type order struct {
+	key  string
+	desc bool
+}
+
+// Params is a parsed and validated list request.
+type Params struct {
+	spec    Spec
+	Limit   int
+	Offset  int
+	cursor  *cursor
+	orders  []order
+	filters []func(*gorm.DB) *gorm.DB
+}
+
+// Meta is returned next to the rows of every list endpoint.
+type Meta struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset,omitempty"`
+	Sort       string `json:"sort"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Page is the list response envelope.
+type Page struct {
+	Data interface{} `json:"data"`
+	Meta Meta        `json:"meta"`
+}
+
+// pagingParams are the query parameters that mark a client as aware of
+// paging.
+var pagingParams = []string{"limit", "offset", "cursor", "sort"}
+
+// WriteList writes a page for the list endpoints that returned a bare JSON
+// array before paging was added. Requests carrying limit, offset, cursor or
+// sort get the Page envelope; older clients keep getting a bare array, of the
+// first page only. Both get the total in X-Total-Count and, when there is
+// more, the next page in a Link header.
+func WriteList(w http.ResponseWriter, r *http.Request, data interface{}, meta Meta) {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(meta.Total, 10))
+	if meta.NextCursor != "" {
+		q := r.URL.Query()
+		q.Del("offset")
+		q.Set("cursor", meta.NextCursor)
+		w.Header().Set("Link", "<"+r.URL.Path+"?"+q.Encode()+`>; rel="next"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	for _, name := range pagingParams {
+		if q.Has(name) {
+			json.NewEncoder(w).Encode(Page{Data: data, Meta: meta})
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(data)
+}
+
+// Parse reads limit, offset, cursor, sort and the spec's filters from the
+// request's query string. Offset and cursor are mutually exclusive.
+func Parse(r *http.Request, spec Spec) (*Params, error) {
+	q := r.URL.Query()
+	p := &Params{spec: spec, Limit: DefaultLimit}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxLimit {
+			return nil, apierror.New(apierror.CodeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(MaxLimit))
+		}
+		p.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, apierror.New(apierror.CodeInvalidParameter, "offset must be a non-negative integer")
+		}
+		p.Offset = n
+	}
+
+	sort := q.Get("sort")
+	if sort == "" {
+		sort = spec.DefaultSort
+	}
+	if err := p.parseSort(sort); err != nil {
+		return nil, err
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		if p.Offset != 0 {
+			return nil, apierror.New(apierror.CodeInvalidParameter, "cursor and offset cannot be combined")
+		}
+		c, err := decodeCursor(v)
+		if err != nil || c.Sort != p.sortString() || len(c.Values) != len(p.orders) {
+			return nil, apierror.New(apierror.CodeInvalidParameter, "cursor is invalid or was issued for a different sort")
+		}
+		p.cursor = c
+	}
+
+	for name, f := range spec.Filters {
+		if err := p.parseFilter(q, name, f); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Params) parseSort(sort string) error {
+	seen := map[string]bool{}
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		o := order{key: strings.TrimPrefix(part, "-"), desc: strings.HasPrefix(part, "-")}
+		if _, ok := p.spec.Sorts[o.key]; !ok {
+			return apierror.New(apierror.CodeInvalidParameter, "cannot sort by "+o.key)
+		}
+		if seen[o.key] {
+			continue
+		}
+		seen[o.key] = true
+		p.orders = append(p.orders, o)
+	}
+	if !seen[p.spec.Key] {
+		p.orders = append(p.orders, order{key: p.spec.Key})
+	}
+	return nil
+}
+
+func (p *Params) sortString() string {
+	parts := make([]string, len(p.orders))
+	for i, o := range p.orders {
+		if o.desc {
+			parts[i] = "-" + o.key
+		} else {
+			parts[i] = o.key
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (p *Params) parseFilter(q map[string][]string, name string, f Filter) error {
+	get := func(k string) string {
+		if v := q[k]; len(v) > 0 {
+			return strings.TrimSpace(v[0])
+		}
+		return ""
+	}
+
+	switch f.Kind {
+	case Equals:
+		v := get(name)
+		if v == "" {
+			return nil
+		}
+		values, err := filterValues(strings.Split(v, ","), f.Type)
+		if err != nil {
+			return apierror.New(apierror.CodeInvalidParameter, name+" "+err.Error())
+		}
+		column := f.Column
+		p.filters = append(p.filters, func(db *gorm.DB) *gorm.DB {
+			if len(values) == 1 {
+				return db.Where(column+" = ?", values[0])
+			}
+			return db.Where(column+" IN ?", values)
+		})
+	case DateRange:
+		for _, bound := range []struct {
+			suffix string
+			op     string
+		}{{"_from", ">="}, {"_to", "<="}} {
+			v := get(name + bound.suffix)
+			if v == "" {
+				continue
+			}
+			t, dateOnly, err := ParseTime(v)
+			if err != nil {
+				return apierror.New(apierror.CodeInvalidParameter, name+bound.suffix+" must be YYYY-MM-DD or RFC 3339")
+			}
+			op := bound.op
+			if dateOnly && op == "<=" {
+				// Make a bare end date cover the whole day.
+				t = t.AddDate(0, 0, 1)
+				op = "<"
+			}
+			column := f.Column
+			p.filters = append(p.filters, func(db *gorm.DB) *gorm.DB {
+				return db.Where(column+" "+op+" ?", t)
+			})
+		}
+	}
+	return nil
+}
+
+// filterValues converts the comma separated values of an Equals filter to
+// typ.
+func filterValues(raw []string, typ ValueType) ([]interface{}, error) {
+	values := make([]interface{}, len(raw))
+	for i, v := range raw {
+		v = strings.TrimSpace(v)
+		switch typ {
+		case Int:
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				return nil, errors.New("must be a comma separated list of integers")
+			}
+			values[i] = n
+		default:
+			values[i] = v
+		}
+	}
+	return values, nil
+}
+
+// ParseTime accepts YYYY-MM-DD, read as midnight in the clinic, or RFC 3339.
+// The bool reports a bare date, so callers can make an end date cover the
+// whole day.
+func ParseTime(v string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", v, clinic.Location()); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
+}
+
+// Filter applies the whitelisted filters. Use it on the base query before
+// counting and before adding a Select.
+func (p *Params) Filter(db *gorm.DB) *gorm.DB {
+	for _, f := range p.filters {
+		db = f(db)
+	}
+	return db
+}
+
+// Page adds ordering, the cursor condition and the limit. It fetches one
+// row more than Limit so Meta can tell whether another page exists.
+func (p *Params) Page(db *gorm.DB) *gorm.DB {
+	for _, o := range p.orders {
+		col := p.spec.Sorts[o.key].Column
+		if o.desc {
+			db = db.Order(col + " DESC")
+		} else {
+			db = db.Order(col + " ASC")
+		}
+	}
+	if p.cursor != nil {
+		sql, args := p.keysetCondition()
+		db = db.Where(sql, args...)
+	}
+	return db.Offset(p.Offset).Limit(p.Limit + 1)
+}
+
+// keysetCondition builds "rows strictly after the cursor" for an ordering
+// with arbitrary directions:
+// (a > x) OR (a = x AND b < y) OR (a = x AND b = y AND c > z) ...
+func (p *Params) keysetCondition() (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, o := range p.orders {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, p.spec.Sorts[p.orders[j].key].Column+" = ?")
+			args = append(args, p.cursor.Values[j])
+		}
+		op := ">"
+		if o.desc {
+			op = "<"
+		}
+		ands = append(ands, p.spec.Sorts[o.key].Column+" "+op+" ?")
+		args = append(args, p.cursor.Values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
@@ -0,0 +1,141 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+)
+
+var testSpec = Spec{
+	Filters: map[string]Filter{
+		"d_id":       {Column: "d_id", Kind: Equals, Type: Int},
+		"status":     {Column: "status", Kind: Equals},
+		"created_at": {Column: "created_at", Kind: DateRange},
+	},
+	Sorts: map[string]Sort{
+		"id":         {Column: "id", Field: "ID"},
+		"created_at": {Column: "created_at", Field: "CreatedAt"},
+	},
+	DefaultSort: "-created_at",
+	Key:         "id",
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		sort    string
+		filters int
+	}{
+		{query: "", sort: "-created_at,id"},
+		{query: "sort=id", sort: "id"},
+		{query: "sort=created_at,created_at", sort: "created_at,id"},
+		{query: "sort=name", wantErr: true},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=201", wantErr: true},
+		{query: "offset=-1", wantErr: true},
+		{query: "offset=5&cursor=abc", wantErr: true},
+		{query: "cursor=not-a-cursor", wantErr: true},
+		{query: "d_id=3", sort: "-created_at,id", filters: 1},
+		{query: "d_id=3,4", sort: "-created_at,id", filters: 1},
+		{query: "d_id=abc", wantErr: true},
+		{query: "d_id=3,x", wantErr: true},
+		{query: "d_id=99999999999", wantErr: true},
+		{query: "status=active,x", sort: "-created_at,id", filters: 1},
+		{query: "created_at_from=2026-01-01&created_at_to=2026-01-31", sort: "-created_at,id", filters: 2},
+		{query: "created_at_from=yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			p, err := Parse(httptest.NewRequest("GET", "/?"+tt.query, nil), testSpec)
+			if tt.wantErr {
+				var apiErr *apierror.Error
+				if !errors.As(err, &apiErr) || apiErr.Code != apierror.CodeInvalidParameter {
+					t.Fatalf("err = %v, want invalid_parameter", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got := p.sortString(); got != tt.sort {
+				t.Errorf("sort = %q, want %q", got, tt.sort)
+			}
+			if len(p.filters) != tt.filters {
+				t.Errorf("%d filters, want %d", len(p.filters), tt.filters)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	type row struct{ ID int }
+	p, err := Parse(httptest.NewRequest("GET", "/?sort=id&limit=2", nil), testSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []row{{1}, {2}, {3}}
+	meta := p.Finish(&rows, 3)
+	if len(rows) != 2 || !meta.HasMore || meta.NextCursor == "" {
+		t.Fatalf("Finish: rows = %v, meta = %+v", rows, meta)
+	}
+
+	next, err := Parse(httptest.NewRequest("GET", "/?sort=id&limit=2&cursor="+meta.NextCursor, nil), testSpec)
+	if err != nil {
+		t.Fatalf("Parse with next_cursor: %v", err)
+	}
+	if next.cursor == nil || len(next.cursor.Values) != 1 || next.cursor.Values[0] != "2" {
+		t.Errorf("cursor = %+v, want the last row's id", next.cursor)
+	}
+
+	if _, err := Parse(httptest.NewRequest("GET", "/?sort=-id&cursor="+meta.NextCursor, nil), testSpec); err == nil {
+		t.Error("cursor accepted for a different sort")
+	}
+}
+
+func TestWriteList(t *testing.T) {
+	rows := []int{1, 2}
+	meta := Meta{Total: 5, Limit: 2, Sort: "id", NextCursor: "abc", HasMore: true}
+
+	tests := []struct {
+		query    string
+		envelope bool
+	}{
+		{"", false},
+		{"status=active", false},
+		{"limit=2", true},
+		{"sort=id", true},
+		{"offset=0", true},
+		{"cursor=xyz", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			WriteList(w, httptest.NewRequest("GET", "/items?"+tt.query, nil), rows, meta)
+
+			if got := w.Header().Get("X-Total-Count"); got != "5" {
+				t.Errorf("X-Total-Count = %q, want 5", got)
+			}
+			if link := w.Header().Get("Link"); !strings.Contains(link, "cursor=abc") || !strings.HasSuffix(link, `rel="next"`) {
+				t.Errorf("Link = %q", link)
+			}
+
+			body := strings.TrimSpace(w.Body.String())
+			if tt.envelope {
+				var page struct {
+					Data []int `json:"data"`
+					Meta Meta  `json:"meta"`
+				}
+				if err := json.Unmarshal([]byte(body), &page); err != nil || len(page.Data) != 2 || page.Meta.Total != 5 {
+					t.Errorf("body = %s, want the page envelope", body)
+				}
+			} else if body != "[1,2]" {
+				t.Errorf("body = %s, want a bare array", body)
+			}
+		})
+	}
+}
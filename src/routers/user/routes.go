@@ -4,17 +4,26 @@ import (
 	addDetailsHandlers "github.com/PragaL15/med_admin_backend/src/handlers/user/AddDetails"
 	appointmentHandlers "github.com/PragaL15/med_admin_backend/src/handlers/user/BookAppointment"
 	dashboardHandlers "github.com/PragaL15/med_admin_backend/src/handlers/user/Dashboard"
+	duplicateHandlers "github.com/PragaL15/med_admin_backend/src/handlers/user/Duplicates"
 	loginHandlers "github.com/PragaL15/med_admin_backend/src/handlers/user/login"
+	notificationHandlers "github.com/PragaL15/med_admin_backend/src/handlers/user/Notifications"
 	recordHandlers "github.com/PragaL15/med_admin_backend/src/handlers/user/record"
 
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	"github.com/PragaL15/med_admin_backend/src/metrics"
 	"github.com/PragaL15/med_admin_backend/src/middleware"
+	"github.com/PragaL15/med_admin_backend/src/storage"
+	"github.com/PragaL15/med_admin_backend/src/tracing"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(db *gorm.DB) *mux.Router {
+func SetupRoutes(db *gorm.DB, store storage.Store) *mux.Router {
     router := mux.NewRouter()
+    router.NotFoundHandler = apierror.NotFoundHandler()
+    router.MethodNotAllowedHandler = apierror.MethodNotAllowedHandler()
 
     corsMiddleware := handlers.CORS(
         handlers.AllowedOrigins([]string{"http://localhost:5173"}), 
@@ -22,9 +31,15 @@ func SetupRoutes(db *gorm.DB) *mux.Router {
         handlers.AllowedHeaders([]string{"Origin", "Content-Type", "Accept", "Authorization"}),
     )
     router.Use(corsMiddleware)
+    router.Use(otelmux.Middleware(tracing.ServiceName))
+    router.Use(metrics.Middleware)
 
     // Public routes
     router.HandleFunc("/login", loginHandlers.Login).Methods("POST")
+    router.Handle("/metrics", metrics.Handler()).Methods("GET")
+    router.HandleFunc("/notifications/delivery", notificationHandlers.RecordDeliveryReport(db)).Methods("POST")
+    router.HandleFunc("/calendar/{token}.ics", recordHandlers.GetCalendarFeed(db)).Methods("GET")
+    router.HandleFunc("/queue/display", appointmentHandlers.GetQueueDisplay(db)).Methods("GET")
 
     apiRouter := router.PathPrefix("/api").Subrouter()
     apiRouter.Use(middleware.RoleBasedAccessMiddleware(db)) 
@@ -32,11 +47,15 @@ func SetupRoutes(db *gorm.DB) *mux.Router {
 
     // Grouped routes
     setupRecordsRoutes(apiRouter.PathPrefix("/records").Subrouter(), db)
-    setupPatientsRoutes(apiRouter.PathPrefix("/patients").Subrouter(), db)
+    setupPatientsRoutes(apiRouter.PathPrefix("/patients").Subrouter(), db, store)
     setupDashboardRoutes(apiRouter.PathPrefix("/dashboard").Subrouter(), db)
     setupDoctorsRoutes(apiRouter.PathPrefix("/doctors").Subrouter(), db)
     setupAppointmentsRoutes(apiRouter.PathPrefix("/appointments").Subrouter(), db)
+    setupWaitlistRoutes(apiRouter.PathPrefix("/waitlist").Subrouter(), db)
+    setupQueueRoutes(apiRouter.PathPrefix("/queue").Subrouter(), db)
+    setupNotificationsRoutes(apiRouter.PathPrefix("/notifications").Subrouter(), db)
     setupAddDetailsRoutes(apiRouter.PathPrefix("/details").Subrouter(), db)
+    setupAdmissionsRoutes(apiRouter.PathPrefix("/admissions").Subrouter(), db)
 
     return router
 }
@@ -45,6 +64,7 @@ func SetupRoutes(db *gorm.DB) *mux.Router {
 func setupRecordsRoutes(router *mux.Router, db *gorm.DB) {
     router.HandleFunc("", recordHandlers.GetRecords(db)).Methods("GET")
     router.HandleFunc("/{id}", recordHandlers.GetRecordByID(db)).Methods("GET")
+    router.HandleFunc("/{id}/documents", recordHandlers.GetRecordDocuments(db)).Methods("GET")
     router.HandleFunc("", recordHandlers.CreateRecord(db)).Methods("POST")
     router.HandleFunc("/{id}", recordHandlers.UpdateRecord(db)).Methods("PUT")
     router.HandleFunc("/{id}", recordHandlers.DeleteRecord(db)).Methods("DELETE")
@@ -52,9 +72,43 @@ func setupRecordsRoutes(router *mux.Router, db *gorm.DB) {
 }
 
 // Patients routes
-func setupPatientsRoutes(router *mux.Router, db *gorm.DB) {
+func setupPatientsRoutes(router *mux.Router, db *gorm.DB, store storage.Store) {
     router.HandleFunc("", recordHandlers.GetAllPatients(db)).Methods("GET")
-    router.HandleFunc("", recordHandlers.CreatePatient(db)).Methods("POST")
+    router.HandleFunc("", addDetailsHandlers.PatientIntake(db)).Methods("POST")
+    router.HandleFunc("/search", recordHandlers.SearchPatients(db)).Methods("GET")
+    router.HandleFunc("/duplicates", duplicateHandlers.GetDuplicateQueue(db)).Methods("GET")
+    router.HandleFunc("/duplicates/{id}/dismiss", duplicateHandlers.DismissDuplicate(db)).Methods("POST")
+    router.HandleFunc("/merge", duplicateHandlers.MergePatients(db)).Methods("POST")
+    router.HandleFunc("/identifiers/lookup", recordHandlers.LookupPatientByIdentifier(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/identifiers", recordHandlers.GetPatientIdentifiers(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/identifiers", recordHandlers.CreatePatientIdentifier(db)).Methods("POST")
+    router.HandleFunc("/{p_id}/identifiers/{id}", recordHandlers.DeletePatientIdentifier(db)).Methods("DELETE")
+    router.HandleFunc("/{p_id}/contacts", recordHandlers.GetPatientContacts(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/contacts", recordHandlers.CreatePatientContact(db)).Methods("POST")
+    router.HandleFunc("/{p_id}/contacts/{id}", recordHandlers.UpdatePatientContact(db)).Methods("PUT")
+    router.HandleFunc("/{p_id}/contacts/{id}", recordHandlers.DeletePatientContact(db)).Methods("DELETE")
+    router.HandleFunc("/{p_id}/allergies", recordHandlers.GetPatientAllergies(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/allergies", recordHandlers.CreatePatientAllergy(db)).Methods("POST")
+    router.HandleFunc("/{p_id}/allergies/{id}", recordHandlers.UpdatePatientAllergy(db)).Methods("PUT")
+    router.HandleFunc("/{p_id}/problems", recordHandlers.GetPatientProblems(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/problems", recordHandlers.CreatePatientProblem(db)).Methods("POST")
+    router.HandleFunc("/{p_id}/problems/{id}", recordHandlers.UpdatePatientProblem(db)).Methods("PUT")
+    router.HandleFunc("/{p_id}/vitals", recordHandlers.GetPatientVitals(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/vitals", recordHandlers.CreatePatientVital(db)).Methods("POST")
+    router.HandleFunc("/{p_id}/vitals/trend", recordHandlers.GetPatientVitalTrend(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/timeline", recordHandlers.GetPatientTimeline(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/appointments", appointmentHandlers.GetPatientAppointments(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/calendar-tokens", recordHandlers.GetPatientCalendarTokens(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/calendar-tokens", recordHandlers.CreatePatientCalendarToken(db)).Methods("POST")
+    router.HandleFunc("/{p_id}/calendar-tokens/{token_id}", recordHandlers.RevokePatientCalendarToken(db)).Methods("DELETE")
+    router.HandleFunc("/{p_id}/consents", recordHandlers.GetPatientConsents(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/consents", recordHandlers.CreatePatientConsent(db)).Methods("POST")
+    router.HandleFunc("/{p_id}/consents/{id}/withdraw", recordHandlers.WithdrawPatientConsent(db)).Methods("POST")
+    router.HandleFunc("/{p_id}/export", recordHandlers.ExportPatient(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/documents", recordHandlers.GetPatientDocuments(db)).Methods("GET")
+    router.HandleFunc("/{p_id}/documents", recordHandlers.UploadPatientDocument(db, store)).Methods("POST")
+    router.HandleFunc("/{p_id}/documents/{id}", recordHandlers.DownloadPatientDocument(db, store)).Methods("GET")
+    router.HandleFunc("/{p_id}/documents/{id}", recordHandlers.DeletePatientDocument(db, store)).Methods("DELETE")
     router.HandleFunc("/{p_id}", recordHandlers.GetPatientByID(db)).Methods("GET")
     router.HandleFunc("/{id}", recordHandlers.UpdatePatient(db)).Methods("PUT")
     router.HandleFunc("/{id}", recordHandlers.DeletePatient(db)).Methods("DELETE")
@@ -70,8 +124,44 @@ func setupDashboardRoutes(router *mux.Router, db *gorm.DB) {
 
 // Appointments routes
 func setupAppointmentsRoutes(router *mux.Router, db *gorm.DB) {
+    router.HandleFunc("", appointmentHandlers.GetAppointments(db)).Methods("GET")
+    router.HandleFunc("", appointmentHandlers.CreateAppointment(db)).Methods("POST")
     router.HandleFunc("/create", appointmentHandlers.CreateAppointment(db)).Methods("POST", "OPTIONS")
     router.HandleFunc("/doctors-patients", appointmentHandlers.GetDoctorsAndPatients(db)).Methods("GET")
+    router.HandleFunc("/series", appointmentHandlers.CreateAppointmentSeries(db)).Methods("POST")
+    router.HandleFunc("/series/{id}", appointmentHandlers.GetAppointmentSeries(db)).Methods("GET")
+    router.HandleFunc("/series/{id}", appointmentHandlers.EditAppointmentSeries(db)).Methods("PUT")
+    router.HandleFunc("/series/{id}/cancel", appointmentHandlers.CancelAppointmentSeries(db)).Methods("POST")
+    router.HandleFunc("/{id}", appointmentHandlers.GetAppointment(db)).Methods("GET")
+    router.HandleFunc("/{id}", appointmentHandlers.UpdateAppointment(db)).Methods("PUT")
+    router.HandleFunc("/{id}", appointmentHandlers.DeleteAppointment(db)).Methods("DELETE")
+    router.HandleFunc("/{id}/reschedule", appointmentHandlers.RescheduleAppointment(db)).Methods("POST")
+    router.HandleFunc("/{id}/cancel", appointmentHandlers.CancelAppointment(db)).Methods("POST")
+    router.HandleFunc("/{id}/status", appointmentHandlers.UpdateAppointmentStatus(db)).Methods("POST")
+    router.HandleFunc("/{id}/check-in", appointmentHandlers.CheckInAppointment(db)).Methods("POST")
+    router.HandleFunc("/{id}/history", appointmentHandlers.GetAppointmentHistory(db)).Methods("GET")
+    router.HandleFunc("/{id}/ics", appointmentHandlers.GetAppointmentICS(db)).Methods("GET")
+}
+
+// Waitlist routes
+func setupWaitlistRoutes(router *mux.Router, db *gorm.DB) {
+    router.HandleFunc("", appointmentHandlers.GetWaitlist(db)).Methods("GET")
+    router.HandleFunc("", appointmentHandlers.AddToWaitlist(db)).Methods("POST")
+    router.HandleFunc("/offers", appointmentHandlers.GetWaitlistOffers(db)).Methods("GET")
+    router.HandleFunc("/offers/{id}/accept", appointmentHandlers.AcceptWaitlistOffer(db)).Methods("POST")
+    router.HandleFunc("/offers/{id}/decline", appointmentHandlers.DeclineWaitlistOffer(db)).Methods("POST")
+    router.HandleFunc("/{id}", appointmentHandlers.RemoveFromWaitlist(db)).Methods("DELETE")
+}
+
+// Queue routes
+func setupQueueRoutes(router *mux.Router, db *gorm.DB) {
+    router.HandleFunc("/walk-ins", appointmentHandlers.AddWalkIn(db)).Methods("POST")
+}
+
+// Notifications routes
+func setupNotificationsRoutes(router *mux.Router, db *gorm.DB) {
+    router.HandleFunc("", notificationHandlers.GetNotifications(db)).Methods("GET")
+    router.HandleFunc("/{id}", notificationHandlers.GetNotification(db)).Methods("GET")
 }
 
 // Doctors routes
@@ -81,10 +171,27 @@ func setupDoctorsRoutes(router *mux.Router, db *gorm.DB) {
     router.HandleFunc("/{id}", recordHandlers.GetDoctorByID(db)).Methods("GET")
     router.HandleFunc("/{id}", recordHandlers.UpdateDoctor(db)).Methods("PUT")
     router.HandleFunc("/{id}", recordHandlers.DeleteDoctor(db)).Methods("DELETE")
+    router.HandleFunc("/{id}/appointments", appointmentHandlers.GetDoctorAppointments(db)).Methods("GET")
+    router.HandleFunc("/{id}/queue", appointmentHandlers.GetDoctorQueue(db)).Methods("GET")
+    router.HandleFunc("/{id}/queue/next", appointmentHandlers.CallNextPatient(db)).Methods("POST")
+    router.HandleFunc("/{id}/calendar-tokens", recordHandlers.GetDoctorCalendarTokens(db)).Methods("GET")
+    router.HandleFunc("/{id}/calendar-tokens", recordHandlers.CreateDoctorCalendarToken(db)).Methods("POST")
+    router.HandleFunc("/{id}/calendar-tokens/{token_id}", recordHandlers.RevokeDoctorCalendarToken(db)).Methods("DELETE")
+    router.HandleFunc("/{id}/availability", recordHandlers.GetDoctorAvailability(db)).Methods("GET")
+    router.HandleFunc("/{id}/schedule", recordHandlers.GetDoctorSchedule(db)).Methods("GET")
+    router.HandleFunc("/{id}/schedule/weekly", recordHandlers.PutDoctorWeeklySchedule(db)).Methods("PUT")
+    router.HandleFunc("/{id}/schedule/overrides", recordHandlers.CreateDoctorOverride(db)).Methods("POST")
+    router.HandleFunc("/{id}/schedule/overrides/{override_id}", recordHandlers.DeleteDoctorOverride(db)).Methods("DELETE")
+    router.HandleFunc("/{id}/leave", recordHandlers.CreateDoctorLeave(db)).Methods("POST")
+    router.HandleFunc("/{id}/leave/{leave_id}", recordHandlers.DeleteDoctorLeave(db)).Methods("DELETE")
 }
 
 // Add Details route
 func setupAddDetailsRoutes(router *mux.Router, db *gorm.DB) {
-    router.HandleFunc("/patientDetails", addDetailsHandlers.AddPatient(db)).Methods("POST", "OPTIONS")
+    router.HandleFunc("/patientDetails", addDetailsHandlers.PatientIntake(db)).Methods("POST", "OPTIONS")
 }
 
+// Admissions routes
+func setupAdmissionsRoutes(router *mux.Router, db *gorm.DB) {
+    router.HandleFunc("/{id}/vitals", recordHandlers.GetAdmissionVitals(db)).Methods("GET")
+}
@@ -0,0 +1,389 @@
+// Package appointments holds the booking rules shared by the appointment
+// handlers: creating, rescheduling, cancelling and the status lifecycle,
+// with every change written to the appointment's history.
+package appointments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	"github.com/PragaL15/med_admin_backend/src/events"
+	"github.com/PragaL15/med_admin_backend/src/metrics"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const DefaultDurationMinutes = 15
+
+var (
+	ErrNotFound        = errors.New("appointment not found")
+	ErrPatientNotFound = errors.New("patient not found")
+	ErrPatientMerged   = errors.New("patient has been merged into another record")
+	ErrDoctorNotFound  = errors.New("doctor not found")
+	ErrNotChangeable   = errors.New("appointment can no longer be changed")
+	ErrUnchanged       = errors.New("new time and doctor are the same as the current ones")
+	ErrInPast          = errors.New("appointments cannot be moved into the past")
+	ErrNotDeletable    = errors.New("only appointments that have not taken place can be deleted")
+)
+
+// StartSQL and EndSQL are an appointment's start and end instants in SQL,
+// for range filters and overlap checks.
+const (
+	StartSQL = `appointments.starts_at`
+	EndSQL   = `appointments.ends_at`
+)
+
+// ParseClock reads a time of day, such as a schedule window's, as
+// "15:04:05", with fractional seconds as time columns come back, or as
+// "15:04".
+func ParseClock(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"15:04:05.999999", "15:04"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time of day %q", s)
+}
+
+// SetStart sets the appointment's start, in the clinic time zone, and its
+// end from its duration.
+func SetStart(a *models.Appointment, start time.Time) {
+	a.StartsAt = clinic.In(start)
+	a.EndsAt = a.StartsAt.Add(time.Duration(a.DurationMinutes) * time.Minute)
+}
+
+// Localize puts the appointment's times in the clinic time zone, so the API
+// shows the clinic's UTC offset whatever the database session returned.
+func Localize(a *models.Appointment) {
+	a.StartsAt, a.EndsAt = clinic.In(a.StartsAt), clinic.In(a.EndsAt)
+}
+
+// CheckParticipants checks that the patient exists and was not merged into
+// another record, and that the doctor exists.
+func CheckParticipants(tx *gorm.DB, pid, did int) error {
+	var patient models.Patient
+	if err := tx.Select("p_id", "merged_into_p_id").Where("p_id = ?", pid).First(&patient).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPatientNotFound
+		}
+		return err
+	}
+	if patient.MergedInto != nil {
+		return ErrPatientMerged
+	}
+	return checkDoctor(tx, did)
+}
+
+func checkDoctor(tx *gorm.DB, did int) error {
+	var count int64
+	if err := tx.Model(&models.Doctor{}).Where("d_id = ?", did).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrDoctorNotFound
+	}
+	return nil
+}
+
+// Row is an appointment as the API lists it: with the patient's name and
+// number, the start formatted for display in the clinic, and the statuses
+// it can move to.
+type Row struct {
+	models.Appointment
+	Date         string   `gorm:"-" json:"date"`
+	Time         string   `gorm:"-" json:"time"`
+	NextStatuses []string `gorm:"-" json:"next_statuses"`
+}
+
+// Fill sets the derived fields of a loaded row.
+func (r *Row) Fill() {
+	Localize(&r.Appointment)
+	r.Date = clinic.FormatDate(r.StartsAt)
+	r.Time = clinic.FormatClock(r.StartsAt)
+	r.NextStatuses = Transitions(strings.ToLower(r.AppoStatus))
+}
+
+// RowColumns is the Select for scanning List into Rows. Add it after
+// counting, since Count does not work with a multi-column Select.
+const RowColumns = "appointments.*, patient_id.p_name, patient_id.p_number"
+
+// List is the base query for appointment lists.
+func List(db *gorm.DB) *gorm.DB {
+	return db.Table("appointments").
+		Joins("LEFT JOIN patient_id ON patient_id.p_id = appointments.p_id")
+}
+
+// Get loads one appointment as a Row.
+func Get(db *gorm.DB, id int) (*Row, error) {
+	var row Row
+	err := List(db).Select(RowColumns).Where("appointments.id = ?", id).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	row.Fill()
+	return &row, nil
+}
+
+// lock loads the appointment row FOR UPDATE inside tx.
+func lock(tx *gorm.DB, id int) (*models.Appointment, error) {
+	var a models.Appointment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Take(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return &a, err
+}
+
+// Create books a, which must carry its start in StartsAt. It returns a
+// *ConflictError when the doctor or the patient is already booked for any
+// part of the slot. Without a status, bookings by patients start as
+// requested and all others as confirmed; patients cannot confirm their own
+// bookings.
+//
+// The booking is counted in metrics.AppointmentsCreated once it commits.
+// When db is already a transaction of the caller's, the caller counts it
+// after committing, as with events.
+func Create(db *gorm.DB, a *models.Appointment, actor Actor) error {
+	if a.DurationMinutes == 0 {
+		a.DurationMinutes = DefaultDurationMinutes
+	}
+	SetStart(a, a.StartsAt)
+	a.AppoStatus = strings.ToLower(a.AppoStatus)
+	if a.AppoStatus == "" {
+		a.AppoStatus = models.StatusConfirmed
+		if actor.Role == RolePatient {
+			a.AppoStatus = models.StatusRequested
+		}
+	}
+	if a.AppoStatus != models.StatusRequested && a.AppoStatus != models.StatusConfirmed {
+		return ErrInitialStatus
+	}
+	if actor.Role == RolePatient && (a.PID != actor.PID || a.AppoStatus != models.StatusRequested) {
+		return ErrNotPermitted
+	}
+	now := time.Now()
+	a.StatusChangedAt = &now
+	nested := inTransaction(db)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := CheckParticipants(tx, a.PID, a.DID); err != nil {
+			return err
+		}
+		if err := lockSlots(tx, a.DID, a.PID); err != nil {
+			return err
+		}
+		if err := checkConflicts(tx, a); err != nil {
+			return err
+		}
+		if err := tx.Create(a).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AppointmentChange{
+			AppointmentID: a.ID,
+			Kind:          models.AppointmentCreated,
+			ToStart:       &a.StartsAt,
+			ToDID:         &a.DID,
+			ToStatus:      a.AppoStatus,
+			ChangedBy:     actor.UserID,
+			ChangedAt:     now,
+		}).Error
+	})
+	if err == nil && !nested {
+		metrics.AppointmentsCreated.Inc()
+	}
+	return err
+}
+
+// inTransaction reports whether db is bound to an open transaction, in
+// which case db.Transaction only sets a savepoint.
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// Details are the fields of an appointment that can be edited in place.
+type Details struct {
+	PHealth     string
+	ProblemHint string
+}
+
+// fieldChange is one edited field in an AppointmentChange.
+type fieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Update edits the free-text details of an open appointment. The history
+// records the old and new value of each field that changed; an edit that
+// changes nothing is not recorded.
+func Update(db *gorm.DB, id int, d Details, actor Actor) (*models.Appointment, error) {
+	var a *models.Appointment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if a, err = lock(tx, id); err != nil {
+			return err
+		}
+		if !actor.owns(a) {
+			return ErrNotPermitted
+		}
+		if isClosed(a.AppoStatus) {
+			return ErrNotChangeable
+		}
+		changed := map[string]fieldChange{}
+		if a.PHealth != d.PHealth {
+			changed["p_health"] = fieldChange{From: a.PHealth, To: d.PHealth}
+		}
+		if a.ProblemHint != d.ProblemHint {
+			changed["problem_hint"] = fieldChange{From: a.ProblemHint, To: d.ProblemHint}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+		fields, err := json.Marshal(changed)
+		if err != nil {
+			return err
+		}
+		a.PHealth, a.ProblemHint = d.PHealth, d.ProblemHint
+		if err := tx.Model(a).Updates(map[string]interface{}{
+			"p_health":     a.PHealth,
+			"problem_hint": a.ProblemHint,
+		}).Error; err != nil {
+			return err
+		}
+		diff := string(fields)
+		return tx.Create(&models.AppointmentChange{
+			AppointmentID: a.ID,
+			Kind:          models.AppointmentUpdated,
+			Fields:        &diff,
+			ChangedBy:     actor.UserID,
+			ChangedAt:     time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reschedule moves an open appointment to start, and to another doctor when
+// did is not zero, subject to the same conflict checks as Create. Only
+// requested and confirmed appointments can be moved, and only to a start
+// that is still ahead. The previous slot is kept in the history.
+func Reschedule(db *gorm.DB, id int, start time.Time, did int, reason string, actor Actor) (*models.Appointment, error) {
+	if !start.After(time.Now()) {
+		return nil, ErrInPast
+	}
+	var a *models.Appointment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if a, err = lock(tx, id); err != nil {
+			return err
+		}
+		if !actor.owns(a) {
+			return ErrNotPermitted
+		}
+		if !reschedulable(a.AppoStatus) {
+			return ErrNotChangeable
+		}
+		fromStart := a.StartsAt
+		fromDID := a.DID
+		if did == 0 {
+			did = a.DID
+		}
+		if fromStart.Equal(start) && fromDID == did {
+			return ErrUnchanged
+		}
+		if did != fromDID {
+			if err := checkDoctor(tx, did); err != nil {
+				return err
+			}
+		}
+
+		SetStart(a, start)
+		a.DID = did
+		if err := lockSlots(tx, a.DID, a.PID); err != nil {
+			return err
+		}
+		if err := checkConflicts(tx, a); err != nil {
+			return err
+		}
+		if err := tx.Model(a).Updates(map[string]interface{}{
+			"starts_at": a.StartsAt,
+			"ends_at":   a.EndsAt,
+			"d_id":      a.DID,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AppointmentChange{
+			AppointmentID: a.ID,
+			Kind:          models.AppointmentRescheduled,
+			FromStart:     &fromStart,
+			ToStart:       &start,
+			FromDID:       &fromDID,
+			ToDID:         &did,
+			Reason:        reason,
+			ChangedBy:     actor.UserID,
+			ChangedAt:     time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Cancel cancels an appointment and records why. Like Transition, it
+// returns the events to publish after the outermost commit.
+func Cancel(db *gorm.DB, id int, reason string, actor Actor) (*models.Appointment, []events.Event, error) {
+	return Transition(db, id, models.StatusCancelled, reason, actor)
+}
+
+// Delete removes an appointment booked in error. Only admins may delete,
+// and only appointments that never took place: requested, confirmed or
+// cancelled ones. The history is kept and ends with the deletion.
+func Delete(db *gorm.DB, id int, reason string, actor Actor) error {
+	if actor.Role != RoleAdmin {
+		return ErrNotPermitted
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		a, err := lock(tx, id)
+		if err != nil {
+			return err
+		}
+		switch a.AppoStatus {
+		case models.StatusRequested, models.StatusConfirmed, models.StatusCancelled:
+		default:
+			return ErrNotDeletable
+		}
+		if err := tx.Delete(a).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AppointmentChange{
+			AppointmentID: a.ID,
+			Kind:          models.AppointmentDeleted,
+			FromStart:     &a.StartsAt,
+			FromDID:       &a.DID,
+			FromStatus:    a.AppoStatus,
+			Reason:        reason,
+			ChangedBy:     actor.UserID,
+			ChangedAt:     time.Now(),
+		}).Error
+	})
+}
+
+// History returns the appointment's changes, oldest first.
+func History(db *gorm.DB, id int) ([]models.AppointmentChange, error) {
+	changes := []models.AppointmentChange{}
+	err := db.Where("appointment_id = ?", id).Order("changed_at, id").Find(&changes).Error
+	return changes, err
+}
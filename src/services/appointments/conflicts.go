@@ -0,0 +1,130 @@
+package appointments
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+)
+
+// BlockingStatuses are the statuses in which an appointment holds its slot.
+var BlockingStatuses = []string{models.StatusRequested, models.StatusConfirmed, models.StatusCheckedIn, models.StatusInConsultation}
+
+// Advisory lock classes, the first key of pg_advisory_xact_lock(int, int).
+const (
+	lockClassDoctor  = 1
+	lockClassPatient = 2
+)
+
+// Conflict is an existing appointment, or a slot held for a waitlisted
+// patient, that overlaps a requested slot. With is "doctor" when it is the
+// same doctor's and "patient" when it is the same patient's; an
+// appointment can be both. Holds only ever conflict with the doctor.
+type Conflict struct {
+	AppointmentID int       `json:"appointment_id,omitempty"`
+	OfferID       int       `json:"offer_id,omitempty"`
+	With          []string  `json:"with"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+}
+
+// ConflictError is returned when a booking would double-book the doctor or
+// the patient.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	parts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		if c.OfferID != 0 {
+			parts[i] = fmt.Sprintf("slot held for waitlist offer %d (%s %s to %s)",
+				c.OfferID, clinic.FormatDate(c.StartsAt), clinic.FormatClock(c.StartsAt), clinic.FormatClock(c.EndsAt))
+			continue
+		}
+		parts[i] = fmt.Sprintf("%s already booked in appointment %d (%s %s to %s)",
+			strings.Join(c.With, " and "), c.AppointmentID, clinic.FormatDate(c.StartsAt), clinic.FormatClock(c.StartsAt), clinic.FormatClock(c.EndsAt))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// lockSlots serialises bookings for the doctor and the patient until tx
+// ends. Doctors are always locked before patients so two bookings cannot
+// wait on each other. An exclusion constraint would also need btree_gist
+// and could not see waitlist holds.
+func lockSlots(tx *gorm.DB, did, pid int) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", lockClassDoctor, did).Error; err != nil {
+		return err
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", lockClassPatient, pid).Error
+}
+
+// checkConflicts returns a *ConflictError when a's slot overlaps another
+// slot-holding appointment of its doctor or its patient, or a pending
+// waitlist offer for its doctor. Slots are half open, so back-to-back
+// appointments do not conflict. The caller must hold lockSlots for a's
+// doctor and patient.
+func checkConflicts(tx *gorm.DB, a *models.Appointment) error {
+	start, end := a.StartsAt, a.EndsAt
+
+	var rows []struct {
+		ID       int       `gorm:"column:id"`
+		DID      int       `gorm:"column:d_id"`
+		PID      int       `gorm:"column:p_id"`
+		StartsAt time.Time `gorm:"column:starts_at"`
+		EndsAt   time.Time `gorm:"column:ends_at"`
+	}
+	err := tx.Table("appointments").
+		Select("id, d_id, p_id, "+StartSQL+" AS starts_at, "+EndSQL+" AS ends_at").
+		Where("appo_status IN ?", BlockingStatuses).
+		Where("(d_id = ? OR p_id = ?)", a.DID, a.PID).
+		Where(StartSQL+" < ? AND "+EndSQL+" > ?", end, start).
+		Where("id <> ?", a.ID).
+		Order("starts_at, id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	var holds []struct {
+		ID       int       `gorm:"column:id"`
+		StartsAt time.Time `gorm:"column:starts_at"`
+		EndsAt   time.Time `gorm:"column:ends_at"`
+	}
+	err = tx.Model(&models.WaitlistOffer{}).
+		Select("id, starts_at, starts_at + duration_minutes * interval '1 minute' AS ends_at").
+		Where("d_id = ? AND status = ? AND expires_at > ?", a.DID, models.OfferPending, time.Now()).
+		Where("starts_at < ? AND starts_at + duration_minutes * interval '1 minute' > ?", end, start).
+		Order("starts_at, id").
+		Scan(&holds).Error
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 && len(holds) == 0 {
+		return nil
+	}
+
+	conflict := &ConflictError{}
+	for _, row := range rows {
+		c := Conflict{AppointmentID: row.ID, StartsAt: clinic.In(row.StartsAt), EndsAt: clinic.In(row.EndsAt)}
+		if row.DID == a.DID {
+			c.With = append(c.With, RoleDoctor)
+		}
+		if row.PID == a.PID {
+			c.With = append(c.With, RolePatient)
+		}
+		conflict.Conflicts = append(conflict.Conflicts, c)
+	}
+	for _, hold := range holds {
+		conflict.Conflicts = append(conflict.Conflicts, Conflict{
+			OfferID:  hold.ID,
+			With:     []string{RoleDoctor},
+			StartsAt: clinic.In(hold.StartsAt),
+			EndsAt:   clinic.In(hold.EndsAt),
+		})
+	}
+	return conflict
+}
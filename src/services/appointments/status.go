@@ -0,0 +1,231 @@
+package appointments
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/events"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+)
+
+// Actor roles, as far as appointments are concerned.
+const (
+	RoleAdmin   = "admin"
+	RoleStaff   = "staff"
+	RoleDoctor  = "doctor"
+	RolePatient = "patient"
+)
+
+// EventCancelled is published after an appointment is cancelled. Payload
+// is a Cancelled.
+const EventCancelled = "appointments.cancelled"
+
+// Cancelled describes the slot a cancellation freed.
+type Cancelled struct {
+	AppointmentID   int       `json:"appointment_id"`
+	PID             int       `json:"p_id"`
+	DID             int       `json:"d_id"`
+	StartsAt        time.Time `json:"starts_at"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Reason          string    `json:"reason"`
+}
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrNotPermitted      = errors.New("not permitted to change this appointment")
+	ErrIllegalTransition = errors.New("illegal status transition")
+	ErrNotStarted        = errors.New("appointment has not started yet")
+	ErrInitialStatus     = errors.New("appointments can only be booked as requested or confirmed")
+)
+
+// Actor is the user making a change. DID and PID are set for users linked to
+// a doctor or a patient.
+type Actor struct {
+	UserID int
+	Role   string
+	DID    int
+	PID    int
+}
+
+// LoadActor classifies the user: an "admin" role name is an admin, a user
+// linked to a doctor is that doctor, one linked to a patient is that
+// patient, and everyone else is front desk staff.
+func LoadActor(db *gorm.DB, userID int) (Actor, error) {
+	var user models.User
+	err := db.Select("user_id", "role_name", "d_id", "p_id").Where("user_id = ?", userID).Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Actor{}, ErrUserNotFound
+	}
+	if err != nil {
+		return Actor{}, err
+	}
+	actor := Actor{UserID: user.UserID, DID: user.DID, PID: user.PID}
+	switch {
+	case strings.EqualFold(user.RoleName, RoleAdmin):
+		actor.Role = RoleAdmin
+	case user.DID != 0:
+		actor.Role = RoleDoctor
+	case user.PID != 0:
+		actor.Role = RolePatient
+	default:
+		actor.Role = RoleStaff
+	}
+	return actor, nil
+}
+
+// owns reports whether the actor may act on a at all. Doctors and patients
+// are limited to their own appointments.
+func (actor Actor) owns(a *models.Appointment) bool {
+	switch actor.Role {
+	case RoleDoctor:
+		return a.DID == actor.DID
+	case RolePatient:
+		return a.PID == actor.PID
+	default:
+		return true
+	}
+}
+
+// transitions lists, for each status, the statuses it may move to and the
+// roles allowed to make that move.
+var transitions = map[string]map[string][]string{
+	models.StatusRequested: {
+		models.StatusConfirmed: {RoleAdmin, RoleStaff, RoleDoctor},
+		models.StatusCancelled: {RoleAdmin, RoleStaff, RoleDoctor, RolePatient},
+	},
+	models.StatusConfirmed: {
+		models.StatusCheckedIn: {RoleAdmin, RoleStaff},
+		models.StatusNoShow:    {RoleAdmin, RoleStaff, RoleDoctor},
+		models.StatusCancelled: {RoleAdmin, RoleStaff, RoleDoctor, RolePatient},
+	},
+	models.StatusCheckedIn: {
+		models.StatusInConsultation: {RoleAdmin, RoleDoctor},
+		models.StatusCancelled:      {RoleAdmin, RoleStaff},
+	},
+	models.StatusInConsultation: {
+		models.StatusCompleted: {RoleAdmin, RoleDoctor},
+	},
+}
+
+// Transitions returns the statuses an appointment in status may move to,
+// in lifecycle order.
+func Transitions(status string) []string {
+	next := []string{}
+	for _, s := range models.AppointmentStatuses {
+		if _, ok := transitions[status][s]; ok {
+			next = append(next, s)
+		}
+	}
+	return next
+}
+
+// reschedulable says whether the slot of an appointment in status can still
+// be moved.
+func reschedulable(status string) bool {
+	return status == models.StatusRequested || status == models.StatusConfirmed
+}
+
+// isClosed says whether the appointment is history.
+func isClosed(status string) bool {
+	return len(transitions[status]) == 0
+}
+
+// checkTransition returns nil when actor may move a to status.
+func checkTransition(a *models.Appointment, to string, actor Actor) error {
+	from := strings.ToLower(a.AppoStatus)
+	roles, ok := transitions[from][to]
+	if !ok {
+		return fmt.Errorf("%w: %s to %s", ErrIllegalTransition, from, to)
+	}
+	if !contains(roles, actor.Role) || !actor.owns(a) {
+		return ErrNotPermitted
+	}
+	if to == models.StatusNoShow && time.Now().Before(a.StartsAt) {
+		return ErrNotStarted
+	}
+	return nil
+}
+
+// Transition moves an appointment to status to. The move is timestamped in
+// the history with the acting user and the optional reason; cancellations
+// also fill the cancellation columns.
+//
+// Transition does not publish anything itself, since db may be inside a
+// transaction of the caller's. It returns the events the move causes, such
+// as EventCancelled, for the caller to publish once the outermost
+// transaction has committed.
+func Transition(db *gorm.DB, id int, to, reason string, actor Actor) (*models.Appointment, []events.Event, error) {
+	to = strings.ToLower(to)
+	var a *models.Appointment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if a, err = lock(tx, id); err != nil {
+			return err
+		}
+		if err := checkTransition(a, to, actor); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		from := a.AppoStatus
+		a.AppoStatus = to
+		a.StatusChangedAt = &now
+		updates := map[string]interface{}{
+			"appo_status":       a.AppoStatus,
+			"status_changed_at": a.StatusChangedAt,
+		}
+		kind := models.AppointmentStatusChanged
+		if to == models.StatusCancelled {
+			kind = models.AppointmentCancelled
+			a.CancelReason = reason
+			a.CancelledAt = &now
+			a.CancelledBy = &actor.UserID
+			updates["cancel_reason"] = a.CancelReason
+			updates["cancelled_at"] = a.CancelledAt
+			updates["cancelled_by"] = a.CancelledBy
+		}
+		if err := tx.Model(a).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AppointmentChange{
+			AppointmentID: a.ID,
+			Kind:          kind,
+			FromStatus:    from,
+			ToStatus:      to,
+			Reason:        reason,
+			ChangedBy:     actor.UserID,
+			ChangedAt:     now,
+		}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var caused []events.Event
+	if to == models.StatusCancelled {
+		caused = append(caused, cancelledEvent(a))
+	}
+	return a, caused, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func cancelledEvent(a *models.Appointment) events.Event {
+	return events.Event{Type: EventCancelled, Payload: Cancelled{
+		AppointmentID:   a.ID,
+		PID:             a.PID,
+		DID:             a.DID,
+		StartsAt:        a.StartsAt,
+		DurationMinutes: a.DurationMinutes,
+		Reason:          a.CancelReason,
+	}}
+}
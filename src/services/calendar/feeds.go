@@ -0,0 +1,233 @@
+package calendar
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"gorm.io/gorm"
+)
+
+const (
+	// Feeds cover appointments from feedPastDays ago to feedFutureDays
+	// ahead.
+	feedPastDays   = 30
+	feedFutureDays = 365
+
+	defaultUIDDomain = "med-admin.local"
+)
+
+var (
+	ErrNotFound      = errors.New("appointment not found")
+	ErrTokenNotFound = errors.New("calendar token not found")
+)
+
+// uidDomain is the right-hand side of event UIDs. It must stay the same for
+// the life of the installation, or clients see every event as new. Set it
+// with CALENDAR_UID_DOMAIN.
+func uidDomain() string {
+	if v := strings.TrimSpace(os.Getenv("CALENDAR_UID_DOMAIN")); v != "" {
+		return v
+	}
+	return defaultUIDDomain
+}
+
+// UID is the iCalendar UID of an appointment. It never changes, so
+// reschedules and cancellations update the event already in a calendar.
+func UID(appointmentID int) string {
+	return fmt.Sprintf("appointment-%d@%s", appointmentID, uidDomain())
+}
+
+// entry is an appointment with what its event shows.
+type entry struct {
+	ID         int
+	AppoStatus string
+	PName      string
+	MRN        string
+	DName      string
+	StartsAt   time.Time
+	EndsAt     time.Time
+	UpdatedAt  time.Time
+	Sequence   int
+}
+
+// entries selects appointments as entries. The sequence counts the changes
+// that move or cancel the appointment.
+func entries(db *gorm.DB) *gorm.DB {
+	return appointments.List(db).
+		Joins("LEFT JOIN doctor_id ON doctor_id.d_id = appointments.d_id").
+		Select("appointments.id, appointments.appo_status, appointments.updated_at, "+
+			"patient_id.p_name, patient_id.mrn, doctor_id.d_name, "+
+			appointments.StartSQL+" AS starts_at, "+appointments.EndSQL+" AS ends_at, "+
+			"(SELECT COUNT(*) FROM appointment_changes c WHERE c.appointment_id = appointments.id AND c.kind IN ?) AS sequence",
+			[]string{models.AppointmentRescheduled, models.AppointmentCancelled})
+}
+
+func status(appoStatus string) string {
+	switch strings.ToLower(appoStatus) {
+	case models.StatusRequested:
+		return StatusTentative
+	case models.StatusCancelled, models.StatusNoShow:
+		return StatusCancelled
+	default:
+		return StatusConfirmed
+	}
+}
+
+// event renders e as seen by owner: doctors see the patient, patients the
+// doctor. Feeds are fetched by calendar providers outside the clinic, so
+// doctors get only the patient's initials and MRN and no clinical notes.
+func (e entry) event(owner string) Event {
+	ev := Event{
+		UID:      UID(e.ID),
+		Sequence: e.Sequence,
+		Start:    e.StartsAt,
+		End:      e.EndsAt,
+		Stamp:    e.UpdatedAt,
+		Status:   status(e.AppoStatus),
+	}
+	if owner == models.CalendarDoctor {
+		ev.Summary = "Appointment: " + initials(e.PName)
+		if e.MRN != "" {
+			ev.Summary += " (" + e.MRN + ")"
+		}
+	} else {
+		ev.Summary = "Appointment with Dr. " + e.DName
+	}
+	return ev
+}
+
+// initials shortens a name to its initials, as in "A. K.".
+func initials(name string) string {
+	var parts []string
+	for _, word := range strings.Fields(name) {
+		r, _ := utf8.DecodeRuneInString(word)
+		parts = append(parts, string(unicode.ToUpper(r))+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Feed renders the owner's calendar feed.
+func Feed(db *gorm.DB, owner string, ownerID int) ([]byte, error) {
+	column := "appointments.p_id"
+	if owner == models.CalendarDoctor {
+		column = "appointments.d_id"
+	}
+	now := time.Now()
+	var rows []entry
+	err := entries(db).
+		Where(column+" = ?", ownerID).
+		Where(appointments.StartSQL+" BETWEEN ? AND ?", now.AddDate(0, 0, -feedPastDays), now.AddDate(0, 0, feedFutureDays)).
+		Order("starts_at, appointments.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, len(rows))
+	for i, row := range rows {
+		events[i] = row.event(owner)
+	}
+	name := "My appointments"
+	if owner == models.CalendarDoctor {
+		name = "Appointments"
+	}
+	var buf bytes.Buffer
+	err = Write(&buf, name, events)
+	return buf.Bytes(), err
+}
+
+// AppointmentICS renders one appointment as a patient's .ics file, as
+// attached to booking confirmations and sent again on changes.
+func AppointmentICS(db *gorm.DB, id int) ([]byte, error) {
+	var row entry
+	err := entries(db).Where("appointments.id = ?", id).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = Write(&buf, "", []Event{row.event(models.CalendarPatient)})
+	return buf.Bytes(), err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue creates a feed token for owner. The token is returned once; only its
+// hash is kept.
+func Issue(db *gorm.DB, owner string, ownerID int, label string, userID int) (string, *models.CalendarToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	t := &models.CalendarToken{
+		Owner:     owner,
+		OwnerID:   ownerID,
+		TokenHash: hashToken(token),
+		Label:     label,
+		CreatedBy: userID,
+	}
+	if err := db.Create(t).Error; err != nil {
+		return "", nil, err
+	}
+	return token, t, nil
+}
+
+// Tokens lists the owner's tokens, revoked ones included.
+func Tokens(db *gorm.DB, owner string, ownerID int) ([]models.CalendarToken, error) {
+	tokens := []models.CalendarToken{}
+	err := db.Where("owner = ? AND owner_id = ?", owner, ownerID).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Revoke stops a token from opening its feed.
+func Revoke(db *gorm.DB, owner string, ownerID, id, userID int) (*models.CalendarToken, error) {
+	var t models.CalendarToken
+	err := db.Where("id = ? AND owner = ? AND owner_id = ?", id, owner, ownerID).Take(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.RevokedAt != nil {
+		return &t, nil
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	t.RevokedBy = &userID
+	err = db.Model(&t).Updates(map[string]interface{}{"revoked_at": t.RevokedAt, "revoked_by": t.RevokedBy}).Error
+	return &t, err
+}
+
+// Resolve returns the live token matching token and records its use.
+func Resolve(db *gorm.DB, token string) (*models.CalendarToken, error) {
+	var t models.CalendarToken
+	err := db.Where("token_hash = ? AND revoked_at IS NULL", hashToken(token)).Take(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	t.LastUsedAt = &now
+	return &t, db.Model(&t).Update("last_used_at", t.LastUsedAt).Error
+}
@@ -0,0 +1,102 @@
+// Package calendar renders appointments as iCalendar (RFC 5545) and keeps
+// the revocable tokens that authenticate doctors' and patients'
+// subscription feeds.
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const prodID = "-//med_admin_backend//Appointments//EN"
+
+// Event statuses.
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Event is one VEVENT. Calendar clients match updates to events by UID and
+// apply the one with the highest Sequence.
+type Event struct {
+	UID         string
+	Sequence    int
+	Start       time.Time
+	End         time.Time
+	Stamp       time.Time
+	Summary     string
+	Description string
+	Status      string
+}
+
+// Write writes a VCALENDAR holding events. name, when set, is the title
+// calendar clients show for a subscribed feed.
+func Write(w io.Writer, name string, events []Event) error {
+	b := bufio.NewWriter(w)
+	line := func(s string) { writeFolded(b, s) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:" + prodID)
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if name != "" {
+		line("X-WR-CALNAME:" + escape(name))
+		line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+		line("X-PUBLISHED-TTL:PT1H")
+	}
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+		line("DTSTAMP:" + utc(e.Stamp))
+		line("DTSTART:" + utc(e.Start))
+		line("DTEND:" + utc(e.End))
+		line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escape(e.Description))
+		}
+		line("STATUS:" + e.Status)
+		line("TRANSP:OPAQUE")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.Flush()
+}
+
+// utc formats t as a UTC date-time, which every calendar client shows in
+// its own zone.
+func utc(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// writeFolded writes a content line with CRLF, folding it so no line is
+// longer than 75 octets and never splitting a UTF-8 sequence.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts.
+		limit = 74
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
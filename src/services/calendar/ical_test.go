@@ -0,0 +1,122 @@
+package calendar
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Follow-up", "Follow-up"},
+		{"Dr. Rao; room 4", `Dr. Rao\; room 4`},
+		{"Cardiology, OPD", `Cardiology\, OPD`},
+		{`C:\reports`, `C:\\reports`},
+		{"line one\nline two", `line one\nline two`},
+		{"line one\r\nline two", `line one\nline two`},
+		{"line one\rline two", `line one\nline two`},
+		{`a\;b`, `a\\\;b`},
+		{"Ärztin: Müller", "Ärztin: Müller"},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFolded(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		lines int
+	}{
+		{"short", "SUMMARY:Check-up", 1},
+		{"exactly 75 octets", "SUMMARY:" + strings.Repeat("a", 67), 1},
+		{"76 octets", "SUMMARY:" + strings.Repeat("a", 68), 2},
+		{"continuations hold 74 octets", "DESCRIPTION:" + strings.Repeat("a", 63+74), 2},
+		{"one more octet folds again", "DESCRIPTION:" + strings.Repeat("a", 63+75), 3},
+		{"long", "DESCRIPTION:" + strings.Repeat("0123456789", 30), 5},
+		{"two-octet runes on the boundary", "SUMMARY:" + strings.Repeat("é", 40), 2},
+		{"three-octet runes", "SUMMARY:" + strings.Repeat("あ", 60), 3},
+		{"four-octet runes", "SUMMARY:" + strings.Repeat("🩺", 30), 2},
+		{"mixed widths", "SUMMARY:a" + strings.Repeat("aé€🩺", 20), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeFolded(w, tt.in)
+			w.Flush()
+			out := buf.String()
+
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("output %q does not end in CRLF", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if len(lines) != tt.lines {
+				t.Errorf("%d lines, want %d", len(lines), tt.lines)
+			}
+			for i, l := range lines {
+				if len(l) > 75 {
+					t.Errorf("line %d is %d octets", i, len(l))
+				}
+				if i > 0 && !strings.HasPrefix(l, " ") {
+					t.Errorf("continuation line %d does not start with a space", i)
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, l)
+				}
+			}
+			if unfolded := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); unfolded != tt.in {
+				t.Errorf("unfolded = %q, want %q", unfolded, tt.in)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := Write(&buf, "Clinic, OPD", []Event{{
+		UID:         "appointment-42@med_admin_backend",
+		Sequence:    2,
+		Start:       start,
+		End:         start.Add(15 * time.Minute),
+		Stamp:       start,
+		Summary:     "Appointment: R. K. (MRN-2026-000042)",
+		Description: strings.Repeat("Bring previous reports; fasting\n", 5),
+		Status:      StatusConfirmed,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Clinic\\, OPD\r\n",
+		"SEQUENCE:2\r\n",
+		"DTSTART:20260302T093000Z\r\n",
+		"DTEND:20260302T094500Z\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Error("output has a bare LF")
+	}
+	for i, l := range strings.Split(out, "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("line %d is %d octets", i, len(l))
+		}
+	}
+}
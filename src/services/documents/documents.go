@@ -0,0 +1,133 @@
+// Package documents stores patient and record documents: it enforces the
+// size limit and content type allowlist, sniffs the MIME type, checksums the
+// bytes and keeps the metadata row and stored object in step.
+package documents
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/storage"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const defaultMaxBytes = 20 << 20
+
+var (
+	ErrTooLarge           = errors.New("document exceeds the size limit")
+	ErrEmpty              = errors.New("document is empty")
+	ErrUnsupportedType    = errors.New("document type is not allowed")
+	ErrRecordNotOfPatient = errors.New("record does not belong to this patient")
+)
+
+// AllowedTypes are the sniffed MIME types accepted for upload.
+var AllowedTypes = []string{"application/pdf", "image/png", "image/jpeg", "image/gif", "image/webp", "text/plain"}
+
+// MaxBytes is the upload size limit. Override with DOCUMENT_MAX_BYTES.
+func MaxBytes() int64 {
+	if v := os.Getenv("DOCUMENT_MAX_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("Ignoring invalid DOCUMENT_MAX_BYTES %q", v)
+	}
+	return defaultMaxBytes
+}
+
+// Upload spools src to a temporary file while hashing it, sniffs its type,
+// stores it and then inserts doc. If the insert fails the stored object is
+// removed again.
+func Upload(ctx context.Context, db *gorm.DB, store storage.Store, doc *models.Document, src io.Reader) error {
+	if doc.RecordID != nil {
+		var count int64
+		if err := db.Table("record").Where("id = ? AND p_id = ?", *doc.RecordID, doc.PID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrRecordNotOfPatient
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "document-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	max := MaxBytes()
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(src, max+1))
+	if err != nil {
+		return err
+	}
+	if size > max {
+		return ErrTooLarge
+	}
+	if size == 0 {
+		return ErrEmpty
+	}
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !allowed(contentType) {
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	doc.ID = 0
+	doc.ContentType = contentType
+	doc.Size = size
+	doc.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	doc.StorageKey = fmt.Sprintf("patients/%d/%s", doc.PID, uuid.NewString())
+	if err := store.Put(ctx, doc.StorageKey, tmp, size, contentType); err != nil {
+		return fmt.Errorf("error storing document: %w", err)
+	}
+	if err := db.Create(doc).Error; err != nil {
+		if derr := store.Delete(ctx, doc.StorageKey); derr != nil {
+			log.Printf("Error removing orphaned document %s: %v", doc.StorageKey, derr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the metadata row and then the stored object. A failure to
+// remove the object is logged, not returned: the document is already gone
+// from the API's point of view.
+func Delete(ctx context.Context, db *gorm.DB, store storage.Store, doc *models.Document) error {
+	if err := db.Delete(doc).Error; err != nil {
+		return err
+	}
+	if err := store.Delete(ctx, doc.StorageKey); err != nil {
+		log.Printf("Error removing stored document %s: %v", doc.StorageKey, err)
+	}
+	return nil
+}
+
+func allowed(contentType string) bool {
+	base := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	for _, t := range AllowedTypes {
+		if base == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/storage"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var pdf = []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\ntrailer\n<<>>\n%%EOF\n")
+
+// testDB is a DryRun database: statements are built but never sent, so the
+// service can be exercised without Postgres.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.Open("host=localhost dbname=test"), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestUpload(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	doc := &models.Document{PID: 7, FileName: "lab.pdf"}
+
+	if err := Upload(ctx, testDB(t), store, doc, bytes.NewReader(pdf)); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if doc.ContentType != "application/pdf" {
+		t.Errorf("ContentType = %q, want application/pdf", doc.ContentType)
+	}
+	if doc.Size != int64(len(pdf)) {
+		t.Errorf("Size = %d, want %d", doc.Size, len(pdf))
+	}
+	sum := sha256.Sum256(pdf)
+	if doc.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("SHA256 = %s, want %x", doc.SHA256, sum)
+	}
+	if !strings.HasPrefix(doc.StorageKey, "patients/7/") {
+		t.Errorf("StorageKey = %q, want a patients/7/ key", doc.StorageKey)
+	}
+
+	rc, err := store.Get(ctx, doc.StorageKey)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(got, pdf) {
+		t.Errorf("stored bytes differ from the upload")
+	}
+	if ct := store.contentType(doc.StorageKey); ct != "application/pdf" {
+		t.Errorf("stored content type = %q, want application/pdf", ct)
+	}
+}
+
+func TestUploadRejected(t *testing.T) {
+	t.Setenv("DOCUMENT_MAX_BYTES", "64")
+	tests := []struct {
+		name string
+		body []byte
+		want error
+	}{
+		{"empty", nil, ErrEmpty},
+		{"over the limit", append(append([]byte{}, pdf...), bytes.Repeat([]byte(" "), 64)...), ErrTooLarge},
+		{"exactly the limit", append(append([]byte{}, pdf...), bytes.Repeat([]byte(" "), 64-len(pdf))...), nil},
+		{"html", []byte("<html><body>hi</body></html>"), ErrUnsupportedType},
+		{"zip", []byte("PK\x03\x04\x14\x00\x00\x00\x08\x00"), ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			doc := &models.Document{PID: 1, FileName: "f"}
+			err := Upload(context.Background(), testDB(t), store, doc, bytes.NewReader(tt.body))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Upload: err = %v, want %v", err, tt.want)
+			}
+			want := 0
+			if tt.want == nil {
+				want = 1
+			}
+			if store.len() != want {
+				t.Errorf("stored %d objects, want %d", store.len(), want)
+			}
+		})
+	}
+}
+
+// stubRecordOwner answers the record ownership count as if record recordID
+// belonged to patient owner, and reports whether the count ran.
+func stubRecordOwner(t *testing.T, db *gorm.DB, recordID int, owner uint) *bool {
+	t.Helper()
+	checked := new(bool)
+	err := db.Callback().Query().After("gorm:query").Register("test:record_owner", func(tx *gorm.DB) {
+		count, ok := tx.Statement.Dest.(*int64)
+		if !ok || tx.Statement.Table != "record" {
+			return
+		}
+		*checked = true
+		*count = 0
+		if vars := tx.Statement.Vars; len(vars) == 2 && vars[0] == recordID && vars[1] == owner {
+			*count = 1
+		}
+		tx.RowsAffected = 1
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return checked
+}
+
+func TestUploadRecordOwnership(t *testing.T) {
+	tests := []struct {
+		name string
+		pid  uint
+		want error
+	}{
+		{"record of the patient", 1, nil},
+		{"record of another patient", 2, ErrRecordNotOfPatient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			checked := stubRecordOwner(t, db, 3, 1)
+			store := newMemoryStore()
+			recordID := 3
+			doc := &models.Document{PID: tt.pid, RecordID: &recordID, FileName: "f"}
+
+			err := Upload(context.Background(), db, store, doc, bytes.NewReader(pdf))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Upload: err = %v, want %v", err, tt.want)
+			}
+			if !*checked {
+				t.Error("record ownership was not checked")
+			}
+			want := 0
+			if tt.want == nil {
+				want = 1
+			}
+			if store.len() != want {
+				t.Errorf("stored %d objects, want %d", store.len(), want)
+			}
+		})
+	}
+}
+
+func TestUploadInsertFails(t *testing.T) {
+	db := testDB(t)
+	insertErr := errors.New("insert failed")
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+		tx.AddError(insertErr)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newMemoryStore()
+	doc := &models.Document{PID: 1, FileName: "f"}
+	if err := Upload(context.Background(), db, store, doc, bytes.NewReader(pdf)); !errors.Is(err, insertErr) {
+		t.Fatalf("Upload: err = %v, want %v", err, insertErr)
+	}
+	if store.len() != 0 {
+		t.Errorf("orphaned object left in the store")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	store := newMemoryStore()
+	doc := &models.Document{PID: 1, FileName: "f"}
+	if err := Upload(ctx, db, store, doc, bytes.NewReader(pdf)); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	doc.ID = 1
+
+	if err := Delete(ctx, db, store, doc); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, doc.StorageKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	tests := []struct {
+		env  string
+		want int64
+	}{
+		{"", defaultMaxBytes},
+		{"1024", 1024},
+		{"0", defaultMaxBytes},
+		{"-5", defaultMaxBytes},
+		{"lots", defaultMaxBytes},
+	}
+	for _, tt := range tests {
+		t.Setenv("DOCUMENT_MAX_BYTES", tt.env)
+		if got := MaxBytes(); got != tt.want {
+			t.Errorf("MaxBytes() with %q = %d, want %d", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/pdf", true},
+		{"text/plain; charset=utf-8", true},
+		{"image/png", true},
+		{"text/html; charset=utf-8", false},
+		{"application/zip", false},
+		{"application/octet-stream", false},
+	}
+	for _, tt := range tests {
+		if got := allowed(tt.contentType); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
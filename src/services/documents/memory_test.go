@@ -0,0 +1,68 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/PragaL15/med_admin_backend/src/storage"
+)
+
+// memoryStore is a storage.Store that keeps objects in memory, standing in
+// for Local or S3 in tests.
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string]memoryObject
+}
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+}
+
+var _ storage.Store = (*memoryStore)(nil)
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{objects: map[string]memoryObject{}}
+}
+
+func (m *memoryStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = memoryObject{data: data, contentType: contentType}
+	return nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.objects[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(o.data)), nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memoryStore) contentType(key string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.objects[key].contentType
+}
+
+func (m *memoryStore) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.objects)
+}
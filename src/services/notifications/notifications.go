@@ -0,0 +1,480 @@
+// Package notifications sends patients appointment reminders, booking
+// confirmations and change notices, and waitlist offers in their spoken
+// language, over the channels they consented to, and
+// tracks each message until the provider reports it delivered or failed.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	"github.com/PragaL15/med_admin_backend/src/events"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/notify"
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"github.com/PragaL15/med_admin_backend/src/services/calendar"
+	"github.com/PragaL15/med_admin_backend/src/services/patients"
+	"github.com/PragaL15/med_admin_backend/src/services/waitlist"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	sweepInterval = time.Minute
+	// Failed messages are retried after retryAfter, up to maxAttempts sends.
+	maxAttempts = 3
+	retryAfter  = 5 * time.Minute
+	// Change notices are sent for changes made within changeLookback, so a
+	// restart does not resend old ones.
+	changeLookback = time.Hour
+)
+
+var (
+	defaultOffsets  = []time.Duration{24 * time.Hour, 2 * time.Hour}
+	defaultChannels = []string{notify.SMS, notify.Email}
+)
+
+var ErrNotFound = errors.New("notification not found")
+
+// ReminderOffsets are how long before an appointment reminders go out,
+// longest first. Override with REMINDER_OFFSETS, e.g. "48h,3h,30m".
+func ReminderOffsets() []time.Duration {
+	if v := os.Getenv("REMINDER_OFFSETS"); v != "" {
+		var offsets []time.Duration
+		for _, part := range strings.Split(v, ",") {
+			d, err := time.ParseDuration(strings.TrimSpace(part))
+			if err != nil || d <= 0 || d > 30*24*time.Hour {
+				offsets = nil
+				break
+			}
+			offsets = append(offsets, d)
+		}
+		if len(offsets) > 0 {
+			sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+			return offsets
+		}
+		log.Printf("Ignoring invalid REMINDER_OFFSETS %q", v)
+	}
+	return defaultOffsets
+}
+
+// Channels are the channels every message is sent over. Override with
+// NOTIFICATION_CHANNELS, e.g. "sms,webhook".
+func Channels() []string {
+	if v := os.Getenv("NOTIFICATION_CHANNELS"); v != "" {
+		var channels []string
+		for _, part := range strings.Split(v, ",") {
+			name := strings.ToLower(strings.TrimSpace(part))
+			if !contains(notify.ChannelNames, name) {
+				channels = nil
+				break
+			}
+			channels = append(channels, name)
+		}
+		if len(channels) > 0 {
+			return channels
+		}
+		log.Printf("Ignoring invalid NOTIFICATION_CHANNELS %q", v)
+	}
+	return defaultChannels
+}
+
+// Register sends waitlist offers as they are made and starts sending due
+// reminders and change notices and retrying failed messages in the
+// background until ctx is done.
+func Register(ctx context.Context, db *gorm.DB, channels notify.Channels) {
+	events.Subscribe(waitlist.EventOffered, func(ctx context.Context, e events.Event) {
+		offer, ok := e.Payload.(models.WaitlistOffer)
+		if !ok {
+			return
+		}
+		if err := SendOffer(db.WithContext(ctx), channels, offer); err != nil {
+			log.Printf("Error notifying waitlist offer %d: %v", offer.ID, err)
+		}
+	})
+
+	go func() {
+		ticker := time.NewTicker(sweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				db := db.WithContext(ctx)
+				if err := SendDueReminders(db, channels); err != nil {
+					log.Printf("Error sending appointment reminders: %v", err)
+				}
+				if err := SendChangeNotices(db, channels); err != nil {
+					log.Printf("Error sending appointment change notices: %v", err)
+				}
+				if err := RetryFailed(db, channels); err != nil {
+					log.Printf("Error retrying notifications: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// upcoming is an open appointment within reminder range.
+type upcoming struct {
+	ID       int
+	PID      int
+	DID      int
+	StartsAt time.Time
+}
+
+// SendDueReminders sends the reminders that have come due. Each appointment
+// gets the reminder for the shortest offset that has passed, so one booked
+// at short notice gets one reminder rather than one per offset.
+func SendDueReminders(db *gorm.DB, channels notify.Channels) error {
+	offsets := ReminderOffsets()
+	now := time.Now()
+
+	var due []upcoming
+	err := db.Model(&models.Appointment{}).
+		Select("id, p_id, d_id, "+appointments.StartSQL+" AS starts_at").
+		Where("appo_status IN ?", []string{models.StatusRequested, models.StatusConfirmed}).
+		Where(appointments.StartSQL+" > ? AND "+appointments.StartSQL+" <= ?", now, now.Add(offsets[0])).
+		Scan(&due).Error
+	if err != nil {
+		return err
+	}
+
+	for _, a := range due {
+		var offset time.Duration
+		for _, o := range offsets {
+			if !a.StartsAt.Add(-o).After(now) {
+				offset = o
+			}
+		}
+		patient, doctor, err := participants(db, a.PID, a.DID)
+		if err != nil {
+			log.Printf("Error loading participants of appointment %d: %v", a.ID, err)
+			continue
+		}
+		data := templateData{
+			Patient: patient.Name,
+			Doctor:  doctor,
+			Date:    clinic.FormatDate(a.StartsAt),
+			Time:    clinic.FormatClock(a.StartsAt),
+		}
+		for _, channel := range Channels() {
+			id := a.ID
+			n := &models.Notification{
+				PID:           a.PID,
+				AppointmentID: &id,
+				Kind:          models.NotificationReminder,
+				Channel:       channel,
+				DueAt:         a.StartsAt.Add(-offset),
+			}
+			if err := send(db, channels, patient, n, data); err != nil {
+				log.Printf("Error sending %s reminder for appointment %d: %v", channel, a.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// change is a recent booking change the patient is told about.
+type change struct {
+	AppointmentID int
+	Kind          string
+	ChangedAt     time.Time
+	PID           int
+	DID           int
+	StartsAt      time.Time
+}
+
+// changeKinds maps appointment change kinds to the notice sent for them.
+// Bookings and status changes are only noticed once confirmed.
+var changeKinds = map[string]string{
+	models.AppointmentCreated:       models.NotificationConfirmed,
+	models.AppointmentStatusChanged: models.NotificationConfirmed,
+	models.AppointmentRescheduled:   models.NotificationRescheduled,
+	models.AppointmentCancelled:     models.NotificationCancelled,
+}
+
+// SendChangeNotices tells patients their upcoming appointment was confirmed,
+// rescheduled or cancelled, with the .ics file attached. It works from
+// committed appointment_changes rather than events, so a booking rolled back
+// with its series or waitlist offer is never announced. Each change is
+// noticed once, keyed by its changed_at.
+func SendChangeNotices(db *gorm.DB, channels notify.Channels) error {
+	now := time.Now()
+	var changes []change
+	err := db.Table("appointment_changes c").
+		Joins("JOIN appointments ON appointments.id = c.appointment_id").
+		Select("c.appointment_id, c.kind, c.changed_at, appointments.p_id, appointments.d_id, "+appointments.StartSQL+" AS starts_at").
+		Where("c.changed_at > ? AND "+appointments.StartSQL+" > ?", now.Add(-changeLookback), now).
+		Where("(c.kind IN ? AND c.to_status = ?) OR c.kind IN ?",
+			[]string{models.AppointmentCreated, models.AppointmentStatusChanged}, models.StatusConfirmed,
+			[]string{models.AppointmentRescheduled, models.AppointmentCancelled}).
+		Where("NOT EXISTS (SELECT 1 FROM notifications n WHERE n.appointment_id = c.appointment_id AND n.due_at = c.changed_at AND n.kind IN ?)",
+			[]string{models.NotificationConfirmed, models.NotificationRescheduled, models.NotificationCancelled}).
+		Order("c.changed_at, c.id").
+		Scan(&changes).Error
+	if err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		patient, doctor, err := participants(db, c.PID, c.DID)
+		if err != nil {
+			log.Printf("Error loading participants of appointment %d: %v", c.AppointmentID, err)
+			continue
+		}
+		data := templateData{
+			Patient: patient.Name,
+			Doctor:  doctor,
+			Date:    clinic.FormatDate(c.StartsAt),
+			Time:    clinic.FormatClock(c.StartsAt),
+		}
+		for _, channel := range Channels() {
+			id := c.AppointmentID
+			n := &models.Notification{
+				PID:           c.PID,
+				AppointmentID: &id,
+				Kind:          changeKinds[c.Kind],
+				Channel:       channel,
+				DueAt:         c.ChangedAt,
+			}
+			if err := send(db, channels, patient, n, data); err != nil {
+				log.Printf("Error sending %s %s for appointment %d: %v", channel, n.Kind, c.AppointmentID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SendOffer tells the patient about a slot held for them.
+func SendOffer(db *gorm.DB, channels notify.Channels, offer models.WaitlistOffer) error {
+	patient, doctor, err := participants(db, offer.PID, offer.DID)
+	if err != nil {
+		return err
+	}
+	data := templateData{
+		Patient: patient.Name,
+		Doctor:  doctor,
+		Date:    clinic.FormatDate(offer.StartsAt),
+		Time:    clinic.FormatClock(offer.StartsAt),
+		Expires: clinic.FormatClock(offer.ExpiresAt) + " " + clinic.FormatDate(offer.ExpiresAt),
+	}
+	for _, channel := range Channels() {
+		id := offer.ID
+		n := &models.Notification{
+			PID:     offer.PID,
+			OfferID: &id,
+			Kind:    models.NotificationWaitlistOffer,
+			Channel: channel,
+			DueAt:   offer.CreatedAt,
+		}
+		if err := send(db, channels, patient, n, data); err != nil {
+			log.Printf("Error sending %s offer %d: %v", channel, offer.ID, err)
+		}
+	}
+	return nil
+}
+
+func participants(db *gorm.DB, pid, did int) (*models.Patient, string, error) {
+	var patient models.Patient
+	if err := db.Where("p_id = ?", pid).Take(&patient).Error; err != nil {
+		return nil, "", err
+	}
+	var doctor models.Doctor
+	if err := db.Select("d_id", "d_name").Where("d_id = ?", did).Take(&doctor).Error; err != nil {
+		return nil, "", err
+	}
+	return &patient, doctor.DName, nil
+}
+
+// address is where the patient receives messages on channel.
+func address(p *models.Patient, channel string) string {
+	if channel == notify.Email {
+		return strings.TrimSpace(p.Email)
+	}
+	return strings.TrimSpace(p.Phone)
+}
+
+// send records n and sends it. A reminder already recorded for the same
+// appointment, channel and due time is left alone. Messages the patient has
+// not consented to, per channel scope, or has no address for are recorded as
+// skipped.
+func send(db *gorm.DB, channels notify.Channels, p *models.Patient, n *models.Notification, data templateData) error {
+	var err error
+	n.Language = language(p.Language)
+	if n.Subject, n.Body, err = render(n.Language, n.Kind, data); err != nil {
+		return err
+	}
+	n.Recipient = address(p, n.Channel)
+	n.Status = models.NotificationPending
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(n)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return gateAndDeliver(db, channels, p, n)
+}
+
+// gateAndDeliver delivers n unless the patient has not consented to the
+// channel or has no address for it, in which case n is skipped. A failed
+// consent lookup counts as a failed attempt, so the message is retried and
+// never sent unchecked.
+func gateAndDeliver(db *gorm.DB, channels notify.Channels, p *models.Patient, n *models.Notification) error {
+	var denied *patients.ConsentDeniedError
+	err := patients.CheckConsent(db, p.PID, models.ConsentCommunication, n.Channel)
+	switch {
+	case errors.As(err, &denied):
+		return finish(db, n, models.NotificationSkipped, denied.Error())
+	case err != nil:
+		n.Attempts++
+		return finish(db, n, models.NotificationFailed, err.Error())
+	case n.Recipient == "":
+		return finish(db, n, models.NotificationSkipped, "no "+n.Channel+" address on file")
+	}
+	return deliver(db, channels, n)
+}
+
+// deliver hands n to its channel and records the outcome. Change notices
+// carry the appointment's current .ics file.
+func deliver(db *gorm.DB, channels notify.Channels, n *models.Notification) error {
+	m := notify.Message{
+		Channel:   n.Channel,
+		To:        n.Recipient,
+		Subject:   n.Subject,
+		Body:      n.Body,
+		Reference: strconv.Itoa(n.ID),
+	}
+	if n.AppointmentID != nil && n.Kind != models.NotificationReminder {
+		ics, err := calendar.AppointmentICS(db, *n.AppointmentID)
+		if err != nil {
+			n.Attempts++
+			return finish(db, n, models.NotificationFailed, err.Error())
+		}
+		m.Attachments = []notify.Attachment{{
+			Name:        fmt.Sprintf("appointment-%d.ics", *n.AppointmentID),
+			ContentType: "text/calendar; charset=utf-8; method=PUBLISH",
+			Data:        ics,
+		}}
+	}
+	providerID, err := channels.Send(db.Statement.Context, m)
+	n.Attempts++
+	if err != nil {
+		return finish(db, n, models.NotificationFailed, err.Error())
+	}
+	now := time.Now()
+	n.SentAt = &now
+	n.ProviderID = providerID
+	return finish(db, n, models.NotificationSent, "")
+}
+
+func finish(db *gorm.DB, n *models.Notification, status, reason string) error {
+	n.Status = status
+	n.Error = reason
+	return db.Model(n).Updates(map[string]interface{}{
+		"recipient":   n.Recipient,
+		"status":      n.Status,
+		"error":       n.Error,
+		"attempts":    n.Attempts,
+		"sent_at":     n.SentAt,
+		"provider_id": n.ProviderID,
+	}).Error
+}
+
+// RetryFailed resends failed messages whose appointment is still ahead and
+// still open, or, for cancellation notices, cancelled, and offer messages
+// whose offer is still pending and unexpired. Consent and the
+// patient's address are checked again first, since either may have changed
+// since the failed attempt.
+func RetryFailed(db *gorm.DB, channels notify.Channels) error {
+	now := time.Now()
+	var failed []models.Notification
+	err := db.Where("status = ? AND attempts < ? AND updated_at <= ?", models.NotificationFailed, maxAttempts, now.Add(-retryAfter)).
+		Where("(appointment_id IS NULL OR appointment_id IN (SELECT id FROM appointments WHERE "+appointments.StartSQL+" > ? AND "+
+			"(appo_status IN ? OR (notifications.kind = ? AND appo_status = ?))))",
+			now, []string{models.StatusRequested, models.StatusConfirmed}, models.NotificationCancelled, models.StatusCancelled).
+		Where("(offer_id IS NULL OR offer_id IN (SELECT id FROM waitlist_offers WHERE status = ? AND expires_at > ?))",
+			models.OfferPending, now).
+		Find(&failed).Error
+	if err != nil {
+		return err
+	}
+	for i := range failed {
+		n := &failed[i]
+		var p models.Patient
+		if err := db.Where("p_id = ?", n.PID).Take(&p).Error; err != nil {
+			log.Printf("Error loading patient of notification %d: %v", n.ID, err)
+			continue
+		}
+		n.Recipient = address(&p, n.Channel)
+		if err := gateAndDeliver(db, channels, &p, n); err != nil {
+			log.Printf("Error retrying notification %d: %v", n.ID, err)
+		}
+	}
+	return nil
+}
+
+// Report is a provider's delivery report for one message, named by our
+// Reference or by the provider's ID.
+type Report struct {
+	Reference  string
+	ProviderID string
+	Status     string
+	Error      string
+}
+
+// RecordDelivery applies a delivery report. Status is
+// models.NotificationDelivered or models.NotificationFailed; a failure
+// reported for a delivered message is ignored.
+func RecordDelivery(db *gorm.DB, r Report) (*models.Notification, error) {
+	if r.Reference == "" && r.ProviderID == "" {
+		return nil, ErrNotFound
+	}
+	var n models.Notification
+	q := db.Where("provider_id = ?", r.ProviderID)
+	if r.Reference != "" {
+		id, err := strconv.Atoi(r.Reference)
+		if err != nil {
+			return nil, ErrNotFound
+		}
+		q = db.Where("id = ?", id)
+	}
+	if err := q.Take(&n).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if n.Status == models.NotificationDelivered {
+		return &n, nil
+	}
+
+	updates := map[string]interface{}{"status": r.Status, "error": r.Error}
+	if r.Status == models.NotificationDelivered {
+		now := time.Now()
+		n.DeliveredAt = &now
+		updates["delivered_at"] = n.DeliveredAt
+	}
+	n.Status, n.Error = r.Status, r.Error
+	if err := db.Model(&n).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package notifications
+
+import (
+	"strings"
+	"text/template"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+)
+
+const defaultLanguage = "en"
+
+// languages maps the lang_spoken values in use, by name or ISO 639-1 code,
+// to the template language.
+var languages = map[string]string{
+	"en": "en", "english": "en",
+	"hi": "hi", "hindi": "hi",
+	"ta": "ta", "tamil": "ta",
+}
+
+// templateData is what message templates can refer to. Date and Time are
+// already formatted.
+type templateData struct {
+	Patient string
+	Doctor  string
+	Date    string
+	Time    string
+	Expires string
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+func parse(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// templates holds, per language, the subject and body of each kind.
+var templates = map[string]map[string]messageTemplate{
+	"en": {
+		models.NotificationReminder: parse(
+			"Appointment reminder",
+			"Dear {{.Patient}}, this is a reminder of your appointment with Dr. {{.Doctor}} on {{.Date}} at {{.Time}}. "+
+				"Please arrive 10 minutes early. To cancel or reschedule, contact the clinic.",
+		),
+		models.NotificationConfirmed: parse(
+			"Appointment confirmed",
+			"Dear {{.Patient}}, your appointment with Dr. {{.Doctor}} on {{.Date}} at {{.Time}} is confirmed.",
+		),
+		models.NotificationRescheduled: parse(
+			"Appointment rescheduled",
+			"Dear {{.Patient}}, your appointment with Dr. {{.Doctor}} has moved to {{.Date}} at {{.Time}}.",
+		),
+		models.NotificationCancelled: parse(
+			"Appointment cancelled",
+			"Dear {{.Patient}}, your appointment with Dr. {{.Doctor}} on {{.Date}} at {{.Time}} has been cancelled. "+
+				"Contact the clinic to book a new one.",
+		),
+		models.NotificationWaitlistOffer: parse(
+			"An appointment slot is available",
+			"Dear {{.Patient}}, a slot with Dr. {{.Doctor}} on {{.Date}} at {{.Time}} has opened up and is held for you until {{.Expires}}. "+
+				"Contact the clinic to accept it.",
+		),
+	},
+	"hi": {
+		models.NotificationReminder: parse(
+			"अपॉइंटमेंट रिमाइंडर",
+			"प्रिय {{.Patient}}, डॉ. {{.Doctor}} के साथ आपका अपॉइंटमेंट {{.Date}} को {{.Time}} बजे है। "+
+				"कृपया 10 मिनट पहले पहुँचें। रद्द करने या समय बदलने के लिए क्लिनिक से संपर्क करें।",
+		),
+		models.NotificationConfirmed: parse(
+			"अपॉइंटमेंट की पुष्टि",
+			"प्रिय {{.Patient}}, डॉ. {{.Doctor}} के साथ {{.Date}} को {{.Time}} बजे आपके अपॉइंटमेंट की पुष्टि हो गई है।",
+		),
+		models.NotificationRescheduled: parse(
+			"अपॉइंटमेंट का समय बदला गया",
+			"प्रिय {{.Patient}}, डॉ. {{.Doctor}} के साथ आपका अपॉइंटमेंट अब {{.Date}} को {{.Time}} बजे है।",
+		),
+		models.NotificationCancelled: parse(
+			"अपॉइंटमेंट रद्द",
+			"प्रिय {{.Patient}}, डॉ. {{.Doctor}} के साथ {{.Date}} को {{.Time}} बजे का आपका अपॉइंटमेंट रद्द कर दिया गया है। "+
+				"नया अपॉइंटमेंट लेने के लिए क्लिनिक से संपर्क करें।",
+		),
+		models.NotificationWaitlistOffer: parse(
+			"अपॉइंटमेंट स्लॉट उपलब्ध है",
+			"प्रिय {{.Patient}}, डॉ. {{.Doctor}} के साथ {{.Date}} को {{.Time}} बजे का स्लॉट खाली हुआ है और {{.Expires}} तक आपके लिए रखा गया है। "+
+				"इसे स्वीकार करने के लिए क्लिनिक से संपर्क करें।",
+		),
+	},
+	"ta": {
+		models.NotificationReminder: parse(
+			"சந்திப்பு நினைவூட்டல்",
+			"அன்புள்ள {{.Patient}}, டாக்டர் {{.Doctor}} அவர்களுடன் உங்கள் சந்திப்பு {{.Date}} அன்று {{.Time}} மணிக்கு உள்ளது. "+
+				"தயவுசெய்து 10 நிமிடங்கள் முன்னதாக வரவும். ரத்து செய்ய அல்லது நேரத்தை மாற்ற மருத்துவமனையைத் தொடர்பு கொள்ளவும்.",
+		),
+		models.NotificationConfirmed: parse(
+			"சந்திப்பு உறுதிசெய்யப்பட்டது",
+			"அன்புள்ள {{.Patient}}, டாக்டர் {{.Doctor}} அவர்களுடன் {{.Date}} அன்று {{.Time}} மணிக்கான உங்கள் சந்திப்பு உறுதிசெய்யப்பட்டது.",
+		),
+		models.NotificationRescheduled: parse(
+			"சந்திப்பு நேரம் மாற்றப்பட்டது",
+			"அன்புள்ள {{.Patient}}, டாக்டர் {{.Doctor}} அவர்களுடனான உங்கள் சந்திப்பு {{.Date}} அன்று {{.Time}} மணிக்கு மாற்றப்பட்டுள்ளது.",
+		),
+		models.NotificationCancelled: parse(
+			"சந்திப்பு ரத்து செய்யப்பட்டது",
+			"அன்புள்ள {{.Patient}}, டாக்டர் {{.Doctor}} அவர்களுடன் {{.Date}} அன்று {{.Time}} மணிக்கான உங்கள் சந்திப்பு ரத்து செய்யப்பட்டது. "+
+				"புதிய சந்திப்பை பதிவு செய்ய மருத்துவமனையைத் தொடர்பு கொள்ளவும்.",
+		),
+		models.NotificationWaitlistOffer: parse(
+			"சந்திப்பு நேரம் கிடைக்கிறது",
+			"அன்புள்ள {{.Patient}}, டாக்டர் {{.Doctor}} அவர்களுடன் {{.Date}} அன்று {{.Time}} மணிக்கான நேரம் காலியாகியுள்ளது, {{.Expires}} வரை உங்களுக்காக ஒதுக்கப்பட்டுள்ளது. "+
+				"ஏற்றுக்கொள்ள மருத்துவமனையைத் தொடர்பு கொள்ளவும்.",
+		),
+	},
+}
+
+// language picks the template language for a patient's lang_spoken, falling
+// back to English for languages without templates.
+func language(spoken string) string {
+	if lang, ok := languages[strings.ToLower(strings.TrimSpace(spoken))]; ok {
+		return lang
+	}
+	return defaultLanguage
+}
+
+// render fills the kind's template in lang.
+func render(lang, kind string, data templateData) (subject, body string, err error) {
+	t := templates[lang][kind]
+	var s, b strings.Builder
+	if err := t.subject.Execute(&s, data); err != nil {
+		return "", "", err
+	}
+	if err := t.body.Execute(&b, data); err != nil {
+		return "", "", err
+	}
+	return s.String(), b.String(), nil
+}
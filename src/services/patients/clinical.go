@@ -0,0 +1,62 @@
+package patients
+
+import (
+	"strings"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+)
+
+// Clinical is the safety summary shown wherever a patient is about to be
+// seen or prescribed for: active allergies and the active problem list.
+type Clinical struct {
+	Allergies []models.PatientAllergy `json:"allergies"`
+	Problems  []models.PatientProblem `json:"problems"`
+}
+
+// ActiveClinical loads the Clinical summary for each of pids in two queries.
+// Every requested patient has an entry, with empty slices when nothing is
+// recorded.
+func ActiveClinical(db *gorm.DB, pids []uint) (map[uint]*Clinical, error) {
+	out := make(map[uint]*Clinical, len(pids))
+	for _, pid := range pids {
+		out[pid] = &Clinical{Allergies: []models.PatientAllergy{}, Problems: []models.PatientProblem{}}
+	}
+	if len(pids) == 0 {
+		return out, nil
+	}
+
+	var allergies []models.PatientAllergy
+	if err := db.Where("p_id IN ? AND lower(status) = ?", pids, "active").
+		Order("p_id, CASE lower(severity) WHEN 'life_threatening' THEN 0 WHEN 'severe' THEN 1 WHEN 'moderate' THEN 2 ELSE 3 END, substance").
+		Find(&allergies).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range allergies {
+		out[a.PID].Allergies = append(out[a.PID].Allergies, a)
+	}
+
+	var problems []models.PatientProblem
+	if err := db.Where("p_id IN ? AND lower(status) = ?", pids, "active").
+		Order("p_id, onset_date DESC NULLS LAST, description").
+		Find(&problems).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range problems {
+		out[p.PID].Problems = append(out[p.PID].Problems, p)
+	}
+	return out, nil
+}
+
+// AllergyWarnings returns the allergies whose substance is mentioned in a
+// free-text prescription.
+func AllergyWarnings(allergies []models.PatientAllergy, prescription string) []models.PatientAllergy {
+	text := strings.ToLower(prescription)
+	warnings := []models.PatientAllergy{}
+	for _, a := range allergies {
+		if s := strings.ToLower(strings.TrimSpace(a.Substance)); s != "" && strings.Contains(text, s) {
+			warnings = append(warnings, a)
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,133 @@
+package patients
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrConsentNotFound     = errors.New("consent not found")
+	ErrConsentNotGranted   = errors.New("only a granted consent can be withdrawn")
+	ErrConsentStatusOnSave = errors.New("a new consent must be granted or refused")
+)
+
+// ConsentDeniedError is returned by CheckConsent when the patient's current
+// decision forbids the action. Consent holds the decision that applies.
+type ConsentDeniedError struct {
+	Consent models.PatientConsent
+}
+
+func (e *ConsentDeniedError) Error() string {
+	if e.Consent.Status == models.ConsentGranted {
+		return fmt.Sprintf("%s consent expired", e.Consent.Type)
+	}
+	return fmt.Sprintf("%s consent is %s", e.Consent.Type, e.Consent.Status)
+}
+
+// normalizeConsent fills the defaults for a consent about to be stored.
+func normalizeConsent(c *models.PatientConsent, pid uint, recordedBy int, now time.Time) error {
+	c.ID = 0
+	c.PID = pid
+	c.Type = strings.ToLower(c.Type)
+	c.Scope = strings.ToLower(strings.TrimSpace(c.Scope))
+	if c.Scope == "" {
+		c.Scope = models.DefaultConsentScope
+	}
+	c.Status = strings.ToLower(c.Status)
+	if c.Status == "" {
+		c.Status = models.ConsentGranted
+	}
+	if c.Status != models.ConsentGranted && c.Status != models.ConsentRefused {
+		return ErrConsentStatusOnSave
+	}
+	if c.GivenAt.IsZero() {
+		c.GivenAt = now
+	}
+	c.WithdrawnAt, c.WithdrawnBy, c.WithdrawalReason = nil, nil, ""
+	c.RecordedBy = recordedBy
+	c.RecordedAt = now
+	return nil
+}
+
+// RecordConsent stores a new consent decision for the patient.
+func RecordConsent(db *gorm.DB, pid uint, c *models.PatientConsent, recordedBy int) error {
+	if err := normalizeConsent(c, pid, recordedBy, time.Now()); err != nil {
+		return err
+	}
+	return db.Create(c).Error
+}
+
+// WithdrawConsent marks a granted consent as withdrawn. The row is kept so
+// the history shows when and why consent was taken back.
+func WithdrawConsent(db *gorm.DB, pid uint, id int, userID int, reason string) (*models.PatientConsent, error) {
+	var c models.PatientConsent
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND p_id = ?", id, pid).First(&c).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrConsentNotFound
+			}
+			return err
+		}
+		if c.Status != models.ConsentGranted {
+			return ErrConsentNotGranted
+		}
+		now := time.Now()
+		c.Status = models.ConsentWithdrawn
+		c.WithdrawnAt = &now
+		c.WithdrawnBy = &userID
+		c.WithdrawalReason = reason
+		return tx.Model(&c).Updates(map[string]interface{}{
+			"status":            c.Status,
+			"withdrawn_at":      c.WithdrawnAt,
+			"withdrawn_by":      c.WithdrawnBy,
+			"withdrawal_reason": c.WithdrawalReason,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CurrentConsents returns the patient's latest decision for every type and
+// scope they have been asked about.
+func CurrentConsents(db *gorm.DB, pid uint) ([]models.PatientConsent, error) {
+	var consents []models.PatientConsent
+	err := db.Raw(`SELECT DISTINCT ON (type, scope) * FROM patient_consents
+		WHERE p_id = ? ORDER BY type, scope, given_at DESC, id DESC`, pid).
+		Scan(&consents).Error
+	return consents, err
+}
+
+// CheckConsent is the enforcement hook for actions that depend on consent,
+// such as sending reminders (communication) or exporting data
+// (data_sharing). It returns a *ConsentDeniedError when the patient's
+// latest decision for the type, in the general scope or the given one, is
+// refused, withdrawn or expired. A patient who was never asked is not
+// blocked.
+func CheckConsent(db *gorm.DB, pid uint, consentType, scope string) error {
+	scopes := []string{models.DefaultConsentScope}
+	if scope = strings.ToLower(strings.TrimSpace(scope)); scope != "" && scope != models.DefaultConsentScope {
+		scopes = append(scopes, scope)
+	}
+
+	var latest []models.PatientConsent
+	if err := db.Raw(`SELECT DISTINCT ON (scope) * FROM patient_consents
+		WHERE p_id = ? AND type = ? AND scope IN ? ORDER BY scope, given_at DESC, id DESC`,
+		pid, consentType, scopes).Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, c := range latest {
+		if c.Status != models.ConsentGranted || (c.ExpiresAt != nil && c.ExpiresAt.Before(now)) {
+			return &ConsentDeniedError{Consent: c}
+		}
+	}
+	return nil
+}
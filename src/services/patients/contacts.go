@@ -0,0 +1,49 @@
+package patients
+
+import (
+	"errors"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSelfLink     = errors.New("a patient cannot be linked as their own contact")
+	ErrLinkNotFound = errors.New("linked patient not found")
+	ErrLinkMerged   = errors.New("linked patient has been merged")
+)
+
+// PrepareContact checks the patient link on c and fills any missing name,
+// phone, email or address from the linked patient's record. It is a no-op
+// for contacts that are not patients.
+func PrepareContact(db *gorm.DB, pid uint, c *models.PatientContact) error {
+	if c.LinkedPID == nil {
+		return nil
+	}
+	if *c.LinkedPID == pid {
+		return ErrSelfLink
+	}
+	var linked models.Patient
+	if err := db.Where("p_id = ?", *c.LinkedPID).First(&linked).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrLinkNotFound
+		}
+		return err
+	}
+	if linked.MergedInto != nil {
+		return ErrLinkMerged
+	}
+	if c.Name == "" {
+		c.Name = linked.Name
+	}
+	if c.Phone == "" {
+		c.Phone = linked.Phone
+	}
+	if c.Email == "" {
+		c.Email = linked.Email
+	}
+	if c.Address == "" {
+		c.Address = linked.Address
+	}
+	return nil
+}
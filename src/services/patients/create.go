@@ -0,0 +1,26 @@
+package patients
+
+import (
+	"time"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+)
+
+// Create inserts a patient with a server assigned p_id and MRN. Any p_id or
+// MRN supplied by the caller is discarded.
+func Create(db *gorm.DB, p *models.Patient) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		mrn, err := NextMRN(tx, now)
+		if err != nil {
+			return err
+		}
+		p.PID = 0
+		p.MRN = mrn
+		p.MergedInto = nil
+		p.CreatedAt = now
+		p.UpdatedAt = now
+		return tx.Create(p).Error
+	})
+}
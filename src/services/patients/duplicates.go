@@ -0,0 +1,81 @@
+package patients
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultReviewThreshold = 0.6
+	maxCandidates          = 50
+)
+
+// ReviewThreshold is the score at or above which a pair is queued for
+// review. Override with DUPLICATE_REVIEW_THRESHOLD.
+func ReviewThreshold() float64 {
+	if v := os.Getenv("DUPLICATE_REVIEW_THRESHOLD"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 && f <= 1 {
+			return f
+		}
+		log.Printf("Ignoring invalid DUPLICATE_REVIEW_THRESHOLD %q", v)
+	}
+	return defaultReviewThreshold
+}
+
+// FindDuplicates returns existing patients that score at or above the review
+// threshold against p, best match first. Candidates are blocked in SQL on
+// DOB, phone digits, email or trigram name similarity before being scored.
+func FindDuplicates(db *gorm.DB, p models.Patient) ([]Match, error) {
+	var candidates []models.Patient
+	err := db.
+		Where("p_id <> ? AND merged_into_p_id IS NULL", p.PID).
+		Where(db.Where("dob = ?", p.DOB).
+			Or("right(regexp_replace(p_number, '\\D', '', 'g'), 10) = ?", normalizePhone(p.Phone)).
+			Or("lower(p_email) = lower(?)", p.Email).
+			Or("p_name % ?", p.Name)).
+		Limit(maxCandidates).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := ReviewThreshold()
+	var matches []Match
+	for _, c := range candidates {
+		score, reasons := Score(p, c)
+		if score >= threshold {
+			matches = append(matches, Match{Patient: c, Score: score, Reasons: reasons})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, nil
+}
+
+// FlagDuplicates runs FindDuplicates for a newly created patient and adds
+// each match to the review queue. Pairs already queued are left alone.
+func FlagDuplicates(db *gorm.DB, p models.Patient) ([]Match, error) {
+	matches, err := FindDuplicates(db, p)
+	if err != nil || len(matches) == 0 {
+		return matches, err
+	}
+
+	rows := make([]models.DuplicateCandidate, len(matches))
+	for i, m := range matches {
+		rows[i] = models.DuplicateCandidate{
+			PID:          p.PID,
+			CandidatePID: m.Patient.PID,
+			Score:        m.Score,
+			Reasons:      strings.Join(m.Reasons, ","),
+			Status:       models.DuplicatePending,
+		}
+	}
+	err = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+	return matches, err
+}
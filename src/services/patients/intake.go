@@ -0,0 +1,80 @@
+package patients
+
+import (
+	"time"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/utils"
+	"gorm.io/gorm"
+)
+
+// Intake is everything captured when a patient is registered.
+type Intake struct {
+	Patient          models.Patient
+	Contacts         []models.PatientContact
+	EmergencyContact *models.PatientContact
+	Insurance        []models.PatientInsurance
+	Consents         []models.PatientConsent
+}
+
+// RegisterIntake creates the patient and all related rows in one
+// transaction. Age is derived from DOB, p_id and MRN are assigned by the
+// server, and each insurance member ID is registered as an identifier of its
+// payer so it cannot be reused for another patient.
+func RegisterIntake(db *gorm.DB, in *Intake, recordedBy int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		in.Patient.Age = utils.AgeAt(in.Patient.DOB, now)
+		if err := Create(tx, &in.Patient); err != nil {
+			return err
+		}
+		pid := in.Patient.PID
+
+		for i := range in.Contacts {
+			c := &in.Contacts[i]
+			c.ID = 0
+			c.PID = pid
+			c.IsEmergency = false
+			if err := tx.Create(c).Error; err != nil {
+				return err
+			}
+		}
+
+		if c := in.EmergencyContact; c != nil {
+			c.ID = 0
+			c.PID = pid
+			c.IsEmergency = true
+			if err := tx.Create(c).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range in.Insurance {
+			ins := &in.Insurance[i]
+			ins.ID = 0
+			ins.PID = pid
+			if err := tx.Create(ins).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.PatientIdentifier{
+				PID:    pid,
+				Type:   "insurance_member_id",
+				Issuer: ins.Payer,
+				Value:  ins.MemberID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range in.Consents {
+			c := &in.Consents[i]
+			if err := normalizeConsent(c, pid, recordedBy, now); err != nil {
+				return err
+			}
+			if err := tx.Create(c).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,135 @@
+package patients
+
+import (
+	"regexp"
+	"strings"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+)
+
+// Field weights for the duplicate score. They sum to 1, so a score is the
+// weighted share of identifying evidence that agrees. Name similarity alone
+// can never cross the default review threshold.
+const (
+	weightName  = 0.35
+	weightDOB   = 0.30
+	weightPhone = 0.20
+	weightEmail = 0.15
+)
+
+var digitsOnly = regexp.MustCompile(`\D`)
+
+type Match struct {
+	Patient models.Patient `json:"patient"`
+	Score   float64        `json:"score"`
+	Reasons []string       `json:"reasons"`
+}
+
+// Score compares two patient rows and returns a value in [0, 1] with the
+// fields that contributed to it.
+func Score(a, b models.Patient) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	if sim := jaroWinkler(normalizeName(a.Name), normalizeName(b.Name)); sim >= 0.85 {
+		score += weightName * sim
+		reasons = append(reasons, "name")
+	}
+
+	if !a.DOB.IsZero() && !b.DOB.IsZero() {
+		switch {
+		case a.DOB.Equal(b.DOB):
+			score += weightDOB
+			reasons = append(reasons, "dob")
+		case a.DOB.Year() == b.DOB.Year() && int(a.DOB.Month()) == b.DOB.Day() && a.DOB.Day() == int(b.DOB.Month()):
+			// Day and month swapped on entry (DD-MM vs MM-DD).
+			score += weightDOB * 0.7
+			reasons = append(reasons, "dob_transposed")
+		}
+	}
+
+	if pa, pb := normalizePhone(a.Phone), normalizePhone(b.Phone); pa != "" && pa == pb {
+		score += weightPhone
+		reasons = append(reasons, "phone")
+	}
+
+	if ea, eb := strings.ToLower(strings.TrimSpace(a.Email)), strings.ToLower(strings.TrimSpace(b.Email)); ea != "" && ea == eb {
+		score += weightEmail
+		reasons = append(reasons, "email")
+	}
+
+	return score, reasons
+}
+
+func normalizeName(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// normalizePhone keeps the last ten digits so +91 98765 43210 and 09876543210
+// compare equal.
+func normalizePhone(s string) string {
+	d := digitsOnly.ReplaceAllString(s, "")
+	if len(d) > 10 {
+		d = d[len(d)-10:]
+	}
+	return d
+}
+
+func jaroWinkler(a, b string) float64 {
+	if a == b {
+		if a == "" {
+			return 0
+		}
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	la, lb := len(ra), len(rb)
+	if la == 0 || lb == 0 {
+		return 0
+	}
+
+	window := max(la, lb)/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matchA := make([]bool, la)
+	matchB := make([]bool, lb)
+	matches := 0
+	for i := 0; i < la; i++ {
+		lo, hi := max(0, i-window), min(lb, i+window+1)
+		for j := lo; j < hi; j++ {
+			if !matchB[j] && ra[i] == rb[j] {
+				matchA[i], matchB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := 0; i < la; i++ {
+		if !matchA[i] {
+			continue
+		}
+		for !matchB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(la) + m/float64(lb) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, la, lb) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
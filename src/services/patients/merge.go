@@ -0,0 +1,126 @@
+package patients
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSamePatient   = errors.New("cannot merge a patient into itself")
+	ErrNotFound      = errors.New("patient not found")
+	ErrAlreadyMerged = errors.New("patient has already been merged")
+)
+
+// mergedTables lists every table whose p_id column must follow a merge.
+var mergedTables = []string{
+	"record", "appointments", "admitted",
+	"patient_identifiers", "patient_contacts", "patient_insurance", "patient_consents",
+	"patient_allergies", "patient_problems", "vital_signs", "documents",
+	"appointment_series", "waitlist_entries", "waitlist_offers", "notifications",
+}
+
+// Merge re-points all clinical rows from mergedPID to survivorPID, retires
+// the merged patient and writes an audit row, all in one transaction. Both
+// patient rows are locked for the duration so concurrent merges serialise.
+func Merge(db *gorm.DB, survivorPID, mergedPID uint, userID int) (*models.PatientMerge, error) {
+	if survivorPID == mergedPID {
+		return nil, ErrSamePatient
+	}
+
+	var audit models.PatientMerge
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var rows []models.Patient
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("p_id IN ?", []uint{survivorPID, mergedPID}).
+			Order("p_id").
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) != 2 {
+			return ErrNotFound
+		}
+		var merged models.Patient
+		for _, p := range rows {
+			if p.MergedInto != nil {
+				return ErrAlreadyMerged
+			}
+			if p.PID == mergedPID {
+				merged = p
+			}
+		}
+
+		moved := map[string][]int{}
+		for _, table := range mergedTables {
+			var ids []int
+			if err := tx.Table(table).Where("p_id = ?", mergedPID).Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			if err := tx.Table(table).Where("id IN ?", ids).Update("p_id", survivorPID).Error; err != nil {
+				return err
+			}
+			moved[table] = ids
+		}
+
+		// Contacts on other patients that link to the merged record now link
+		// to the survivor.
+		if err := tx.Table("patient_contacts").Where("linked_p_id = ?", mergedPID).Update("linked_p_id", survivorPID).Error; err != nil {
+			return err
+		}
+
+		// Patient logins follow the surviving record as well.
+		if err := tx.Table("user_table").Where("p_id = ?", mergedPID).Update("p_id", survivorPID).Error; err != nil {
+			return err
+		}
+
+		// So do the patient's calendar feeds, which would otherwise show only
+		// what is left under the retired record.
+		if err := tx.Model(&models.CalendarToken{}).Where("owner = ? AND owner_id = ?", models.CalendarPatient, mergedPID).
+			Update("owner_id", survivorPID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Patient{}).Where("p_id = ?", mergedPID).Updates(map[string]interface{}{
+			"merged_into_p_id": survivorPID,
+			"p_status":         "merged",
+			"updatedat":        time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.DuplicateCandidate{}).
+			Where("(p_id = ? AND candidate_p_id = ?) OR (p_id = ? AND candidate_p_id = ?)", mergedPID, survivorPID, survivorPID, mergedPID).
+			Updates(map[string]interface{}{"status": models.DuplicateMerged, "reviewed_by": userID, "reviewed_at": now}).Error; err != nil {
+			return err
+		}
+
+		movedJSON, err := json.Marshal(moved)
+		if err != nil {
+			return err
+		}
+		snapshot, err := json.Marshal(merged)
+		if err != nil {
+			return err
+		}
+		audit = models.PatientMerge{
+			SurvivorPID: survivorPID,
+			MergedPID:   mergedPID,
+			MergedBy:    userID,
+			Moved:       string(movedJSON),
+			Snapshot:    string(snapshot),
+		}
+		return tx.Create(&audit).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &audit, nil
+}
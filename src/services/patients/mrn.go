@@ -0,0 +1,19 @@
+package patients
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/mrn"
+	"gorm.io/gorm"
+)
+
+// NextMRN draws a value from patient_mrn_seq and renders it with the
+// configured format.
+func NextMRN(db *gorm.DB, at time.Time) (string, error) {
+	var seq int64
+	if err := db.Raw(mrn.SequenceSQL).Scan(&seq).Error; err != nil {
+		return "", fmt.Errorf("error drawing MRN sequence: %v", err)
+	}
+	return mrn.Render(seq, at)
+}
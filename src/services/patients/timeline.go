@@ -0,0 +1,62 @@
+package patients
+
+import (
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"gorm.io/gorm"
+)
+
+// Timeline entry types.
+var TimelineTypes = []string{"record", "prescription", "appointment", "admission", "operation"}
+
+// TimelineEntry is one event in a patient's history. Key is unique across
+// the timeline ("<type>:<source_id>") and breaks ties between entries at the
+// same instant.
+type TimelineEntry struct {
+	Key        string    `gorm:"column:entry_key" json:"key"`
+	Type       string    `gorm:"column:type" json:"type"`
+	SourceID   int       `gorm:"column:source_id" json:"source_id"`
+	OccurredAt time.Time `gorm:"column:occurred_at" json:"occurred_at"`
+	DID        *int      `gorm:"column:d_id" json:"d_id,omitempty"`
+	Title      string    `gorm:"column:title" json:"title"`
+	Detail     string    `gorm:"column:detail" json:"detail,omitempty"`
+	Status     string    `gorm:"column:status" json:"status,omitempty"`
+}
+
+// timelineSQL flattens every source table into TimelineEntry columns. A
+// record with a prescription yields both a record and a prescription entry;
+// an admission with an operation yields both an admission and an operation.
+const timelineSQL = `
+SELECT 'record:' || id AS entry_key, 'record' AS type, id AS source_id,
+	date::timestamp AS occurred_at, d_id::integer AS d_id,
+	'Clinical record' AS title, COALESCE(description, '')::text AS detail, ''::text AS status
+FROM record WHERE p_id = @pid
+UNION ALL
+SELECT 'prescription:' || id, 'prescription', id,
+	date::timestamp, d_id::integer,
+	'Prescription', prescription::text, ''::text
+FROM record WHERE p_id = @pid AND COALESCE(prescription, '') <> ''
+UNION ALL
+SELECT 'appointment:' || id, 'appointment', id,
+	` + appointments.StartSQL + `, d_id::integer,
+	COALESCE(NULLIF(problem_hint, ''), 'Appointment')::text, COALESCE(p_health, '')::text, COALESCE(appo_status, '')::text
+FROM appointments WHERE p_id = @pid
+UNION ALL
+SELECT 'admission:' || id, 'admission', id,
+	created_at::timestamp, NULL::integer,
+	('Admitted to ward ' || COALESCE(ward_no, ''))::text, COALESCE(p_health, '')::text, ''::text
+FROM admitted WHERE p_id = @pid
+UNION ALL
+SELECT 'operation:' || id, 'operation', id,
+	p_operation_date::timestamp, NULL::integer,
+	p_operation::text, ('Operated by ' || COALESCE(p_operated_doctor, ''))::text, ''::text
+FROM admitted WHERE p_id = @pid AND COALESCE(p_operation, '') <> '' AND p_operation_date IS NOT NULL
+`
+
+// Timeline returns a query over the patient's timeline, exposed as a table
+// called "timeline" with TimelineEntry columns, ready for filtering and
+// paging.
+func Timeline(db *gorm.DB, pid uint) *gorm.DB {
+	return db.Table("(?) AS timeline", db.Raw(timelineSQL, map[string]interface{}{"pid": pid}))
+}
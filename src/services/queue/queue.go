@@ -0,0 +1,464 @@
+// Package queue runs the outpatient queue: checking patients in for the
+// day's appointments, issuing walk-in tokens, calling the next patient in
+// to the doctor and estimating how long everyone else will wait.
+package queue
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	"github.com/PragaL15/med_admin_backend/src/metrics"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPriority = 3
+
+	// lockClassQueue is the first key of the per-doctor queue advisory lock,
+	// after the appointment doctor and patient classes.
+	lockClassQueue = 3
+
+	// The average consultation is taken over a doctor's last
+	// historyDays, once there are at least minSamples consultations;
+	// until then each appointment's booked duration is used.
+	historyDays = 30
+	minSamples  = 5
+
+	// MaxBoards caps how many doctors one display request may ask for.
+	MaxBoards = 50
+)
+
+var (
+	ErrNotToday      = errors.New("only today's appointments can be checked in")
+	ErrAlreadyQueued = errors.New("patient is already in today's queue")
+	ErrBusy          = errors.New("doctor is still in a consultation; complete it first")
+	ErrEmpty         = errors.New("nobody is waiting")
+	ErrNotPermitted  = errors.New("not permitted to manage the queue")
+	ErrTooManyBoards = errors.New("too many doctors for one display")
+)
+
+// Entry is a patient in a doctor's queue. Position counts from 1 for the
+// next patient in; the patient in consultation has position 0.
+type Entry struct {
+	Position             int        `json:"position"`
+	AppointmentID        int        `json:"appointment_id"`
+	Token                int        `json:"token"`
+	PID                  int        `json:"p_id"`
+	PName                string     `json:"p_name"`
+	Priority             int        `json:"priority"`
+	WalkIn               bool       `json:"walk_in"`
+	Status               string     `json:"status"`
+	StartsAt             time.Time  `json:"starts_at"`
+	CheckedInAt          time.Time  `json:"checked_in_at"`
+	CalledAt             *time.Time `json:"called_at,omitempty"`
+	EstimatedWaitMinutes int        `json:"estimated_wait_minutes"`
+}
+
+// Queue is a doctor's queue for today.
+type Queue struct {
+	DID                        int     `json:"d_id"`
+	DName                      string  `json:"d_name"`
+	AverageConsultationMinutes float64 `json:"average_consultation_minutes,omitempty"`
+	Serving                    *Entry  `json:"serving"`
+	Waiting                    []Entry `json:"waiting"`
+}
+
+// row is a queued appointment as loaded.
+type row struct {
+	ID              int
+	PID             int
+	PName           string
+	DID             int
+	DName           string
+	AppoStatus      string
+	WalkIn          bool
+	QueueToken      int
+	QueuePriority   int
+	DurationMinutes int
+	StartsAt        time.Time
+	CheckedInAt     time.Time
+	CalledAt        *time.Time
+}
+
+// today is the start of the current day in the clinic. Queues and tokens
+// start over every day.
+func today() time.Time {
+	return clinic.Today()
+}
+
+// lockQueue serialises token issue and calls for the doctor until tx ends.
+func lockQueue(tx *gorm.DB, did int) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", lockClassQueue, did).Error
+}
+
+// nextToken is the doctor's next token number today.
+func nextToken(tx *gorm.DB, did int) (int, error) {
+	var last int
+	err := tx.Model(&models.Appointment{}).
+		Select("COALESCE(MAX(queue_token), 0)").
+		Where("d_id = ? AND checked_in_at >= ?", did, today()).
+		Scan(&last).Error
+	return last + 1, err
+}
+
+// queued limits a query on appointments to today's checked-in and
+// in-consultation ones.
+func queued(db *gorm.DB) *gorm.DB {
+	return db.Where("appointments.appo_status IN ? AND appointments.checked_in_at >= ?",
+		[]string{models.StatusCheckedIn, models.StatusInConsultation}, today())
+}
+
+// inQueue selects the queue as rows, in queue order: by priority (1 is the
+// most urgent), then appointment time, then token. Walk-ins are booked for
+// the time they arrive.
+func inQueue(db *gorm.DB) *gorm.DB {
+	return appointments.List(db).
+		Joins("LEFT JOIN doctor_id ON doctor_id.d_id = appointments.d_id").
+		Scopes(queued).
+		Select("appointments.id, appointments.p_id, patient_id.p_name, appointments.d_id, doctor_id.d_name, appointments.appo_status, " +
+			"appointments.walk_in, appointments.queue_token, appointments.queue_priority, appointments.duration_minutes, " +
+			appointments.StartSQL + " AS starts_at, appointments.checked_in_at, appointments.called_at").
+		Order("appointments.queue_priority, starts_at, appointments.queue_token")
+}
+
+// CheckIn checks a confirmed appointment of today in and gives it the
+// doctor's next token. Priority 0 means DefaultPriority.
+func CheckIn(db *gorm.DB, id, priority int, actor appointments.Actor) error {
+	if priority == 0 {
+		priority = DefaultPriority
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockQueueOf(tx, id); err != nil {
+			return err
+		}
+		a, _, err := appointments.Transition(tx, id, models.StatusCheckedIn, "", actor)
+		if err != nil {
+			return err
+		}
+		if !clinic.Midnight(clinic.In(a.StartsAt)).Equal(today()) {
+			return ErrNotToday
+		}
+		token, err := nextToken(tx, a.DID)
+		if err != nil {
+			return err
+		}
+		return tx.Model(a).Updates(map[string]interface{}{
+			"checked_in_at":  a.StatusChangedAt,
+			"queue_token":    token,
+			"queue_priority": priority,
+		}).Error
+	})
+}
+
+// WalkIn is a patient without an appointment joining a doctor's queue.
+type WalkIn struct {
+	PID             int
+	DID             int
+	Priority        int
+	PHealth         string
+	ProblemHint     string
+	DurationMinutes int
+}
+
+// AddWalkIn books the walk-in for now, already checked in, and returns the
+// appointment with its token. Walk-ins join the queue rather than take a
+// slot, so they skip the double-booking checks; a patient can only be in
+// one queue at a time.
+func AddWalkIn(db *gorm.DB, in WalkIn, actor appointments.Actor) (*models.Appointment, error) {
+	if actor.Role != appointments.RoleAdmin && actor.Role != appointments.RoleStaff {
+		return nil, ErrNotPermitted
+	}
+	if in.Priority == 0 {
+		in.Priority = DefaultPriority
+	}
+	if in.DurationMinutes == 0 {
+		in.DurationMinutes = appointments.DefaultDurationMinutes
+	}
+	var a *models.Appointment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := appointments.CheckParticipants(tx, in.PID, in.DID); err != nil {
+			return err
+		}
+		if err := lockQueue(tx, in.DID); err != nil {
+			return err
+		}
+		var count int64
+		err := tx.Model(&models.Appointment{}).Scopes(queued).Where("p_id = ?", in.PID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyQueued
+		}
+		token, err := nextToken(tx, in.DID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		a = &models.Appointment{
+			PID:             in.PID,
+			DID:             in.DID,
+			PHealth:         in.PHealth,
+			ProblemHint:     in.ProblemHint,
+			AppoStatus:      models.StatusCheckedIn,
+			DurationMinutes: in.DurationMinutes,
+			StatusChangedAt: &now,
+			WalkIn:          true,
+			CheckedInAt:     &now,
+			QueueToken:      &token,
+			QueuePriority:   &in.Priority,
+		}
+		appointments.SetStart(a, now)
+		if err := tx.Create(a).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AppointmentChange{
+			AppointmentID: a.ID,
+			Kind:          models.AppointmentCreated,
+			ToStart:       &a.StartsAt,
+			ToDID:         &a.DID,
+			ToStatus:      a.AppoStatus,
+			Reason:        "walk-in",
+			ChangedBy:     actor.UserID,
+			ChangedAt:     now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.AppointmentsCreated.Inc()
+	return a, nil
+}
+
+// CallNext moves the first patient waiting in the doctor's queue into
+// consultation and returns their appointment ID.
+func CallNext(db *gorm.DB, did int, actor appointments.Actor) (int, error) {
+	if actor.Role == appointments.RoleDoctor && actor.DID != did {
+		return 0, ErrNotPermitted
+	}
+	var id int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockQueue(tx, did); err != nil {
+			return err
+		}
+		var next row
+		err := inQueue(tx).
+			Where("appointments.d_id = ? AND appointments.appo_status = ?", did, models.StatusCheckedIn).
+			Limit(1).Scan(&next).Error
+		if err != nil {
+			return err
+		}
+		if next.ID == 0 {
+			return ErrEmpty
+		}
+		id = next.ID
+		return call(tx, id, did, actor)
+	})
+	return id, err
+}
+
+// Call moves a checked-in patient into consultation out of queue order.
+func Call(db *gorm.DB, id int, actor appointments.Actor) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		did, err := lockQueueOf(tx, id)
+		if err != nil {
+			return err
+		}
+		return call(tx, id, did, actor)
+	})
+}
+
+// lockQueueOf takes lockQueue for the doctor of appointment id and returns
+// the doctor. The queue lock is always taken before the appointment row is
+// locked, so check-ins and calls on the same appointment cannot deadlock.
+func lockQueueOf(tx *gorm.DB, id int) (int, error) {
+	var a models.Appointment
+	if err := tx.Select("id", "d_id").Where("id = ?", id).Take(&a).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, appointments.ErrNotFound
+		}
+		return 0, err
+	}
+	return a.DID, lockQueue(tx, a.DID)
+}
+
+// call moves appointment id into consultation with the doctor, who must
+// have completed their previous one. The caller holds lockQueue for did.
+func call(tx *gorm.DB, id, did int, actor appointments.Actor) error {
+	var busy int64
+	err := tx.Model(&models.Appointment{}).Scopes(queued).
+		Where("d_id = ? AND appo_status = ?", did, models.StatusInConsultation).
+		Count(&busy).Error
+	if err != nil {
+		return err
+	}
+	if busy > 0 {
+		return ErrBusy
+	}
+	a, _, err := appointments.Transition(tx, id, models.StatusInConsultation, "", actor)
+	if err != nil {
+		return err
+	}
+	return tx.Model(a).Update("called_at", a.StatusChangedAt).Error
+}
+
+// averageConsultations is each doctor's mean time from being called in to
+// completion. Doctors without enough history are left out.
+func averageConsultations(db *gorm.DB, dids []int) (map[int]time.Duration, error) {
+	var stats []struct {
+		DID     int
+		Samples int64
+		Seconds float64
+	}
+	err := db.Model(&models.Appointment{}).
+		Select("d_id AS d_id, COUNT(*) AS samples, COALESCE(AVG(EXTRACT(EPOCH FROM status_changed_at - called_at)), 0) AS seconds").
+		Where("d_id IN ? AND appo_status = ? AND called_at >= ?", dids, models.StatusCompleted, time.Now().AddDate(0, 0, -historyDays)).
+		Group("d_id").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	averages := map[int]time.Duration{}
+	for _, st := range stats {
+		if st.Samples >= minSamples {
+			averages[st.DID] = time.Duration(st.Seconds * float64(time.Second))
+		}
+	}
+	return averages, nil
+}
+
+// Doctor builds the doctor's queue for today with estimated waits. A
+// patient's wait is what is left of the current consultation plus one
+// consultation for everyone ahead of them.
+func Doctor(db *gorm.DB, did int) (*Queue, error) {
+	var doctor models.Doctor
+	if err := db.Select("d_id", "d_name").Where("d_id = ?", did).Take(&doctor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, appointments.ErrDoctorNotFound
+		}
+		return nil, err
+	}
+	var rows []row
+	if err := inQueue(db).Where("appointments.d_id = ?", did).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	averages, err := averageConsultations(db, []int{did})
+	if err != nil {
+		return nil, err
+	}
+	return build(did, doctor.DName, rows, averages[did]), nil
+}
+
+// build makes the queue of one doctor from their rows in queue order.
+func build(did int, dname string, rows []row, average time.Duration) *Queue {
+	q := &Queue{DID: did, DName: dname, Waiting: []Entry{}, AverageConsultationMinutes: math.Round(average.Minutes()*10) / 10}
+	length := func(r row) time.Duration {
+		if average > 0 {
+			return average
+		}
+		return time.Duration(r.DurationMinutes) * time.Minute
+	}
+	now := time.Now()
+	var ahead time.Duration
+	for _, r := range rows {
+		e := Entry{
+			AppointmentID: r.ID,
+			Token:         r.QueueToken,
+			PID:           r.PID,
+			PName:         strings.TrimSpace(r.PName),
+			Priority:      r.QueuePriority,
+			WalkIn:        r.WalkIn,
+			Status:        r.AppoStatus,
+			StartsAt:      clinic.In(r.StartsAt),
+			CheckedInAt:   clinic.In(r.CheckedInAt),
+		}
+		if r.CalledAt != nil {
+			calledAt := clinic.In(*r.CalledAt)
+			e.CalledAt = &calledAt
+		}
+		if r.AppoStatus == models.StatusInConsultation {
+			if r.CalledAt != nil {
+				if left := length(r) - now.Sub(*r.CalledAt); left > 0 {
+					ahead += left
+				}
+			}
+			serving := e
+			q.Serving = &serving
+			continue
+		}
+		e.Position = len(q.Waiting) + 1
+		e.EstimatedWaitMinutes = int(math.Ceil(ahead.Minutes()))
+		q.Waiting = append(q.Waiting, e)
+		ahead += length(r)
+	}
+	return q
+}
+
+// Board is what a waiting-room screen shows for one doctor: tokens only,
+// no patient details.
+type Board struct {
+	DID     int          `json:"d_id"`
+	DName   string       `json:"d_name"`
+	Serving *int         `json:"serving"`
+	Waiting []BoardEntry `json:"waiting"`
+}
+
+type BoardEntry struct {
+	Token                int `json:"token"`
+	EstimatedWaitMinutes int `json:"estimated_wait_minutes"`
+}
+
+// Boards returns the board of every doctor with a queue today, or of the
+// given doctors only, in d_id order. At most MaxBoards doctors can be given.
+// It loads every queue in one query, so screens polling it cost the same
+// whatever the number of doctors.
+func Boards(db *gorm.DB, dids []int) ([]Board, error) {
+	if len(dids) > MaxBoards {
+		return nil, ErrTooManyBoards
+	}
+	q := inQueue(db)
+	if len(dids) > 0 {
+		q = q.Where("appointments.d_id IN ?", dids)
+	}
+	var rows []row
+	if err := q.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	byDoctor := map[int][]row{}
+	var active []int
+	for _, r := range rows {
+		if _, ok := byDoctor[r.DID]; !ok {
+			active = append(active, r.DID)
+		}
+		byDoctor[r.DID] = append(byDoctor[r.DID], r)
+	}
+	boards := []Board{}
+	if len(active) == 0 {
+		return boards, nil
+	}
+	sort.Ints(active)
+	averages, err := averageConsultations(db, active)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, did := range active {
+		queue := build(did, byDoctor[did][0].DName, byDoctor[did], averages[did])
+		b := Board{DID: queue.DID, DName: queue.DName, Waiting: make([]BoardEntry, len(queue.Waiting))}
+		if queue.Serving != nil {
+			b.Serving = &queue.Serving.Token
+		}
+		for i, e := range queue.Waiting {
+			b.Waiting[i] = BoardEntry{Token: e.Token, EstimatedWaitMinutes: e.EstimatedWaitMinutes}
+		}
+		boards = append(boards, b)
+	}
+	return boards, nil
+}
@@ -0,0 +1,220 @@
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+)
+
+// MaxOccurrences caps one series.
+const MaxOccurrences = 104
+
+// maxPeriods bounds expansion for rules whose periods can be empty, such as
+// BYMONTHDAY=31 every twelfth February.
+const maxPeriods = 12 * MaxOccurrences
+
+var (
+	ErrInvalidRule        = errors.New("invalid recurrence rule")
+	ErrUnbounded          = errors.New("recurrence rule needs COUNT or UNTIL")
+	ErrTooManyOccurrences = fmt.Errorf("recurrence rule yields more than %d occurrences", MaxOccurrences)
+)
+
+// Rule is the subset of RFC 5545 RRULE that series bookings use: FREQ of
+// DAILY, WEEKLY or MONTHLY, INTERVAL, COUNT, UNTIL, BYDAY for weekly rules
+// and BYMONTHDAY for monthly ones. Weeks start on Monday.
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []time.Weekday
+	ByMonthDay []int
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// ParseRule reads "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10", with or without the
+// "RRULE:" prefix.
+func ParseRule(s string) (Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	rule := Rule{Interval: 1}
+	invalid := func(format string, args ...interface{}) (Rule, error) {
+		return Rule{}, fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return invalid("%q is not NAME=VALUE", part)
+		}
+		switch name {
+		case "FREQ":
+			if value != "DAILY" && value != "WEEKLY" && value != "MONTHLY" {
+				return invalid("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 52 {
+				return invalid("INTERVAL must be between 1 and 52")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return invalid("COUNT must be a positive integer")
+			}
+			rule.Count = n
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return invalid("UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ")
+			}
+			rule.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					return invalid("unknown BYDAY %q", d)
+				}
+				if !containsWeekday(rule.ByDay, wd) {
+					rule.ByDay = append(rule.ByDay, wd)
+				}
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n < 1 || n > 31 {
+					return invalid("BYMONTHDAY must be between 1 and 31")
+				}
+				if !containsInt(rule.ByMonthDay, n) {
+					rule.ByMonthDay = append(rule.ByMonthDay, n)
+				}
+			}
+		default:
+			return invalid("%s is not supported", name)
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		return invalid("FREQ is required")
+	case rule.Count == 0 && rule.Until.IsZero():
+		return Rule{}, ErrUnbounded
+	case rule.Count > MaxOccurrences:
+		return Rule{}, ErrTooManyOccurrences
+	case len(rule.ByDay) > 0 && rule.Freq != "WEEKLY":
+		return invalid("BYDAY is only supported with FREQ=WEEKLY")
+	case len(rule.ByMonthDay) > 0 && rule.Freq != "MONTHLY":
+		return invalid("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	return rule, nil
+}
+
+func containsWeekday(days []time.Weekday, d time.Weekday) bool {
+	for _, v := range days {
+		if v == d {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, n int) bool {
+	for _, v := range values {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// parseUntil accepts a date, which includes that whole day in the clinic,
+// or a UTC date-time.
+func parseUntil(v string) (time.Time, error) {
+	if t, err := time.ParseInLocation("20060102", v, clinic.Location()); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Parse("20060102T150405Z", v)
+}
+
+// Occurrences expands the rule from start, which is always the first
+// occurrence. Later occurrences keep start's time of day.
+func (r Rule) Occurrences(start time.Time) ([]time.Time, error) {
+	out := []time.Time{start}
+	emit := func(t time.Time) (bool, error) {
+		if !t.After(start) {
+			return true, nil
+		}
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false, nil
+		}
+		if r.Count > 0 && len(out) >= r.Count {
+			return false, nil
+		}
+		if len(out) >= MaxOccurrences {
+			return false, ErrTooManyOccurrences
+		}
+		out = append(out, t)
+		return true, nil
+	}
+
+	switch r.Freq {
+	case "DAILY":
+		for k := 1; k <= maxPeriods; k++ {
+			if more, err := emit(start.AddDate(0, 0, k*r.Interval)); !more || err != nil {
+				return out, err
+			}
+		}
+	case "WEEKLY":
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		offsets := make([]int, len(days))
+		for i, d := range days {
+			offsets[i] = (int(d) + 6) % 7
+		}
+		sort.Ints(offsets)
+		monday := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		for k := 0; k < maxPeriods; k++ {
+			for _, off := range offsets {
+				if more, err := emit(monday.AddDate(0, 0, k*7*r.Interval+off)); !more || err != nil {
+					return out, err
+				}
+			}
+		}
+	case "MONTHLY":
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		sort.Ints(days)
+		first := time.Date(start.Year(), start.Month(), 1, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+		for k := 0; k < maxPeriods; k++ {
+			month := first.AddDate(0, k*r.Interval, 0)
+			for _, d := range days {
+				t := month.AddDate(0, 0, d-1)
+				if t.Month() != month.Month() {
+					// The month is too short for this day.
+					continue
+				}
+				if more, err := emit(t); !more || err != nil {
+					return out, err
+				}
+			}
+		}
+	}
+	return out, nil
+}
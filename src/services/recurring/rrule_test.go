@@ -0,0 +1,211 @@
+package recurring
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rule
+		wantErr error
+	}{
+		{
+			in:   "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10",
+			want: Rule{Freq: "WEEKLY", Interval: 1, Count: 10, ByDay: []time.Weekday{time.Monday, time.Thursday}},
+		},
+		{
+			in:   "rrule:freq=daily;interval=2;count=3",
+			want: Rule{Freq: "DAILY", Interval: 2, Count: 3},
+		},
+		{
+			in:   "FREQ=WEEKLY;BYDAY=MO,MO,WE;COUNT=4",
+			want: Rule{Freq: "WEEKLY", Interval: 1, Count: 4, ByDay: []time.Weekday{time.Monday, time.Wednesday}},
+		},
+		{
+			in:   "FREQ=MONTHLY;BYMONTHDAY=15,1,15;COUNT=6",
+			want: Rule{Freq: "MONTHLY", Interval: 1, Count: 6, ByMonthDay: []int{15, 1}},
+		},
+		{
+			in:   "FREQ=DAILY;UNTIL=20260305",
+			want: Rule{Freq: "DAILY", Interval: 1, Until: time.Date(2026, 3, 5, 23, 59, 59, 0, time.UTC)},
+		},
+		{
+			in:   "FREQ=DAILY;UNTIL=20260305T120000Z",
+			want: Rule{Freq: "DAILY", Interval: 1, Until: time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)},
+		},
+		{in: "BYDAY=MO;COUNT=2", wantErr: ErrInvalidRule},
+		{in: "FREQ=YEARLY;COUNT=2", wantErr: ErrInvalidRule},
+		{in: "FREQ=WEEKLY", wantErr: ErrUnbounded},
+		{in: "FREQ=WEEKLY;COUNT=105", wantErr: ErrTooManyOccurrences},
+		{in: "FREQ=WEEKLY;COUNT=0", wantErr: ErrInvalidRule},
+		{in: "FREQ=WEEKLY;INTERVAL=53;COUNT=2", wantErr: ErrInvalidRule},
+		{in: "FREQ=WEEKLY;BYDAY=XX;COUNT=2", wantErr: ErrInvalidRule},
+		{in: "FREQ=WEEKLY;BYDAY=1MO;COUNT=2", wantErr: ErrInvalidRule},
+		{in: "FREQ=DAILY;BYDAY=MO;COUNT=2", wantErr: ErrInvalidRule},
+		{in: "FREQ=WEEKLY;BYMONTHDAY=1;COUNT=2", wantErr: ErrInvalidRule},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=32;COUNT=2", wantErr: ErrInvalidRule},
+		{in: "FREQ=DAILY;UNTIL=2026-03-05", wantErr: ErrInvalidRule},
+		{in: "FREQ=DAILY;COUNT", wantErr: ErrInvalidRule},
+		{in: "FREQ=DAILY;COUNT=2;WKST=SU", wantErr: ErrInvalidRule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRule(tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, time.UTC) }
+	ny := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, newYork) }
+
+	tests := []struct {
+		name    string
+		rule    string
+		start   time.Time
+		want    []time.Time
+		wantErr error
+	}{
+		{
+			name:  "daily count",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: utc(2026, 3, 1, 9),
+			want:  []time.Time{utc(2026, 3, 1, 9), utc(2026, 3, 2, 9), utc(2026, 3, 3, 9)},
+		},
+		{
+			name:  "daily interval until date includes the whole day",
+			rule:  "FREQ=DAILY;INTERVAL=2;UNTIL=20260305",
+			start: utc(2026, 3, 1, 17),
+			want:  []time.Time{utc(2026, 3, 1, 17), utc(2026, 3, 3, 17), utc(2026, 3, 5, 17)},
+		},
+		{
+			name:  "until date-time is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20260303T090000Z",
+			start: utc(2026, 3, 1, 9),
+			want:  []time.Time{utc(2026, 3, 1, 9), utc(2026, 3, 2, 9), utc(2026, 3, 3, 9)},
+		},
+		{
+			name:  "count stops before until",
+			rule:  "FREQ=DAILY;COUNT=2;UNTIL=20261231",
+			start: utc(2026, 3, 1, 9),
+			want:  []time.Time{utc(2026, 3, 1, 9), utc(2026, 3, 2, 9)},
+		},
+		{
+			name:  "until stops before count",
+			rule:  "FREQ=WEEKLY;COUNT=10;UNTIL=20260315",
+			start: utc(2026, 3, 2, 9),
+			want:  []time.Time{utc(2026, 3, 2, 9), utc(2026, 3, 9, 9)},
+		},
+		{
+			name:  "weekly defaults to the start's weekday",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			start: utc(2026, 3, 4, 10), // Wednesday
+			want:  []time.Time{utc(2026, 3, 4, 10), utc(2026, 3, 11, 10), utc(2026, 3, 18, 10)},
+		},
+		{
+			name:  "byday in week order",
+			rule:  "FREQ=WEEKLY;BYDAY=TH,MO;COUNT=4",
+			start: utc(2026, 3, 2, 9), // Monday
+			want:  []time.Time{utc(2026, 3, 2, 9), utc(2026, 3, 5, 9), utc(2026, 3, 9, 9), utc(2026, 3, 12, 9)},
+		},
+		{
+			name:  "byday skips days before the start in its week",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3",
+			start: utc(2026, 3, 4, 9), // Wednesday
+			want:  []time.Time{utc(2026, 3, 4, 9), utc(2026, 3, 6, 9), utc(2026, 3, 9, 9)},
+		},
+		{
+			name:  "byday sunday ends the week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,MO;COUNT=4",
+			start: utc(2026, 3, 2, 9), // Monday
+			want:  []time.Time{utc(2026, 3, 2, 9), utc(2026, 3, 8, 9), utc(2026, 3, 16, 9), utc(2026, 3, 22, 9)},
+		},
+		{
+			name:  "monthly skips months without the day",
+			rule:  "FREQ=MONTHLY;COUNT=3",
+			start: utc(2026, 1, 31, 9),
+			want:  []time.Time{utc(2026, 1, 31, 9), utc(2026, 3, 31, 9), utc(2026, 5, 31, 9)},
+		},
+		{
+			name:  "monthly bymonthday",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=15,1;COUNT=4",
+			start: utc(2026, 1, 10, 9),
+			want:  []time.Time{utc(2026, 1, 10, 9), utc(2026, 1, 15, 9), utc(2026, 2, 1, 9), utc(2026, 2, 15, 9)},
+		},
+		{
+			name:  "daily keeps the wall clock across spring forward",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: ny(2026, 3, 7, 9),
+			want:  []time.Time{ny(2026, 3, 7, 9), ny(2026, 3, 8, 9), ny(2026, 3, 9, 9)},
+		},
+		{
+			name:  "weekly keeps the wall clock across fall back",
+			rule:  "FREQ=WEEKLY;BYDAY=SA,MO;COUNT=3",
+			start: ny(2026, 10, 31, 14),
+			want:  []time.Time{ny(2026, 10, 31, 14), ny(2026, 11, 2, 14), ny(2026, 11, 7, 14)},
+		},
+		{
+			name:    "too many occurrences before until",
+			rule:    "FREQ=DAILY;UNTIL=20301231",
+			start:   utc(2026, 1, 1, 9),
+			wantErr: ErrTooManyOccurrences,
+		},
+		{
+			name:  "never past the cap",
+			rule:  "FREQ=DAILY;COUNT=104",
+			start: utc(2026, 1, 1, 9),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRule(%q): %v", tt.rule, err)
+			}
+			got, err := rule.Occurrences(tt.start)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if tt.want == nil {
+				if len(got) != rule.Count {
+					t.Errorf("%d occurrences, want %d", len(got), rule.Count)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
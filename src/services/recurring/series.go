@@ -0,0 +1,368 @@
+// Package recurring books appointment series from RRULE-style patterns and
+// changes or cancels the remainder of a series.
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	"github.com/PragaL15/med_admin_backend/src/events"
+	"github.com/PragaL15/med_admin_backend/src/metrics"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"github.com/PragaL15/med_admin_backend/src/services/schedules"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reasons an occurrence was not booked.
+const (
+	OutsideSchedule = "outside_schedule"
+	DoubleBooked    = "double_booked"
+	InPast          = "in_past"
+)
+
+var (
+	ErrNotFound      = errors.New("series not found")
+	ErrNotInSeries   = errors.New("appointment is not part of this series")
+	ErrNothingBooked = errors.New("no occurrence of the series could be booked")
+	ErrNothingToEdit = errors.New("nothing to change")
+)
+
+// Booking describes a new series. Start is the first occurrence.
+type Booking struct {
+	PID             int
+	DID             int
+	Start           time.Time
+	DurationMinutes int
+	RRule           string
+	PHealth         string
+	ProblemHint     string
+	// SkipUnavailable books the occurrences that fit and reports the rest,
+	// instead of booking nothing when any occurrence does not fit.
+	SkipUnavailable bool
+}
+
+// Skipped is an occurrence that could not be booked or moved.
+type Skipped struct {
+	StartsAt  time.Time               `json:"starts_at"`
+	Reason    string                  `json:"reason"`
+	Conflicts []appointments.Conflict `json:"conflicts,omitempty"`
+}
+
+// OccurrenceError lists the occurrences that stopped a series change.
+type OccurrenceError struct {
+	Skipped []Skipped
+}
+
+func (e *OccurrenceError) Error() string {
+	return fmt.Sprintf("%d occurrences are outside the doctor's schedule or already booked", len(e.Skipped))
+}
+
+// Result is a created series with what was and was not booked.
+type Result struct {
+	Series      models.AppointmentSeries `json:"series"`
+	Occurrences []models.Appointment     `json:"occurrences"`
+	Skipped     []Skipped                `json:"skipped"`
+}
+
+func loadDoctor(tx *gorm.DB, did int) (*models.Doctor, error) {
+	var doctor models.Doctor
+	err := tx.Where("d_id = ?", did).Take(&doctor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, appointments.ErrDoctorNotFound
+	}
+	return &doctor, err
+}
+
+// fits reports whether start..end is bookable in the doctor's schedule.
+func fits(doctor *models.Doctor, schedule *schedules.Schedule, start, end time.Time) bool {
+	return !strings.EqualFold(doctor.DStatus, "inactive") && schedule.Covers(start, end)
+}
+
+// loadSchedule loads the doctor's schedule for the clinic days of the first
+// to the last occurrence. The bounds are whole days, so overrides and leave
+// on the first occurrence's date are included whatever its time of day.
+func loadSchedule(tx *gorm.DB, did int, first, last time.Time) (*schedules.Schedule, error) {
+	return schedules.Load(tx, did, clinic.Midnight(clinic.In(first)), clinic.Midnight(clinic.In(last)))
+}
+
+// Create books every occurrence of the series in one transaction. Each
+// occurrence must fall inside the doctor's schedule and pass the same
+// double-booking checks as a single appointment.
+func Create(db *gorm.DB, b Booking, actor appointments.Actor) (*Result, error) {
+	rule, err := ParseRule(b.RRule)
+	if err != nil {
+		return nil, err
+	}
+	// Expand on the clinic's clocks so occurrences keep their time of day
+	// across daylight saving changes.
+	starts, err := rule.Occurrences(clinic.In(b.Start))
+	if err != nil {
+		return nil, err
+	}
+	if b.DurationMinutes == 0 {
+		b.DurationMinutes = appointments.DefaultDurationMinutes
+	}
+	duration := time.Duration(b.DurationMinutes) * time.Minute
+
+	result := &Result{Occurrences: []models.Appointment{}, Skipped: []Skipped{}}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		doctor, err := loadDoctor(tx, b.DID)
+		if err != nil {
+			return err
+		}
+		schedule, err := loadSchedule(tx, b.DID, starts[0], starts[len(starts)-1])
+		if err != nil {
+			return err
+		}
+
+		result.Series = models.AppointmentSeries{
+			PID:             b.PID,
+			DID:             b.DID,
+			RRule:           strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(b.RRule)), "RRULE:"),
+			FirstStart:      b.Start,
+			DurationMinutes: b.DurationMinutes,
+			PHealth:         b.PHealth,
+			ProblemHint:     b.ProblemHint,
+			CreatedBy:       actor.UserID,
+		}
+		if err := tx.Create(&result.Series).Error; err != nil {
+			return err
+		}
+
+		for _, start := range starts {
+			if !fits(doctor, schedule, start, start.Add(duration)) {
+				result.Skipped = append(result.Skipped, Skipped{StartsAt: start, Reason: OutsideSchedule})
+				continue
+			}
+			a := models.Appointment{
+				PID:             b.PID,
+				DID:             b.DID,
+				PHealth:         b.PHealth,
+				ProblemHint:     b.ProblemHint,
+				DurationMinutes: b.DurationMinutes,
+				SeriesID:        &result.Series.ID,
+			}
+			appointments.SetStart(&a, start)
+			var conflict *appointments.ConflictError
+			if err := appointments.Create(tx, &a, actor); errors.As(err, &conflict) {
+				result.Skipped = append(result.Skipped, Skipped{StartsAt: start, Reason: DoubleBooked, Conflicts: conflict.Conflicts})
+				continue
+			} else if err != nil {
+				return err
+			}
+			result.Occurrences = append(result.Occurrences, a)
+		}
+
+		if len(result.Skipped) > 0 && !b.SkipUnavailable {
+			return &OccurrenceError{Skipped: result.Skipped}
+		}
+		if len(result.Occurrences) == 0 {
+			return ErrNothingBooked
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.AppointmentsCreated.Add(float64(len(result.Occurrences)))
+	return result, nil
+}
+
+// Get returns the series and all its occurrences, in order.
+func Get(db *gorm.DB, id int) (*models.AppointmentSeries, []appointments.Row, error) {
+	var series models.AppointmentSeries
+	if err := db.Where("id = ?", id).Take(&series).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	rows := []appointments.Row{}
+	err := appointments.List(db).Select(appointments.RowColumns).
+		Where("appointments.series_id = ?", id).
+		Order("starts_at, appointments.id").
+		Find(&rows).Error
+	return &series, rows, err
+}
+
+// remainder locks the series and returns the open occurrences starting at
+// or after the fromID occurrence.
+func remainder(tx *gorm.DB, seriesID, fromID int) (*models.AppointmentSeries, []models.Appointment, error) {
+	var series models.AppointmentSeries
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", seriesID).Take(&series).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	var from models.Appointment
+	if err := tx.Where("id = ? AND series_id = ?", fromID, seriesID).Take(&from).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotInSeries
+		}
+		return nil, nil, err
+	}
+
+	var rows []models.Appointment
+	err := tx.Where("series_id = ? AND appo_status IN ?", seriesID, []string{models.StatusRequested, models.StatusConfirmed}).
+		Where(appointments.StartSQL+" >= ?", from.StartsAt).
+		Order("starts_at, id").
+		Find(&rows).Error
+	return &series, rows, err
+}
+
+// CancelRemainder cancels the fromID occurrence and every open occurrence
+// after it. Occurrences already under way or finished are left alone. The
+// cancellations are published once all of them have committed.
+func CancelRemainder(db *gorm.DB, seriesID, fromID int, reason string, actor appointments.Actor) ([]models.Appointment, error) {
+	cancelled := []models.Appointment{}
+	var caused []events.Event
+	err := db.Transaction(func(tx *gorm.DB) error {
+		series, rows, err := remainder(tx, seriesID, fromID)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			a, evs, err := appointments.Cancel(tx, row.ID, reason, actor)
+			if err != nil {
+				return err
+			}
+			cancelled = append(cancelled, *a)
+			caused = append(caused, evs...)
+		}
+		if len(rows) > 0 {
+			series.CancelledFrom = &rows[0].StartsAt
+			series.CancelReason = reason
+			return tx.Model(series).Updates(map[string]interface{}{
+				"cancelled_from": series.CancelledFrom,
+				"cancel_reason":  series.CancelReason,
+			}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	events.PublishAll(db.Statement.Context, caused)
+	return cancelled, nil
+}
+
+// Edit changes the remainder of a series. Clock moves every occurrence to
+// a new time of day on its own date, DID to another doctor; zero values
+// keep the current ones. PHealth and ProblemHint replace the notes when
+// set.
+type Edit struct {
+	Clock       string
+	DID         int
+	PHealth     *string
+	ProblemHint *string
+	Reason      string
+}
+
+// EditRemainder applies e to the fromID occurrence and every open one
+// after it. Moves are checked against the schedule and for double booking
+// like new bookings, and nothing changes unless every occurrence fits.
+func EditRemainder(db *gorm.DB, seriesID, fromID int, e Edit, actor appointments.Actor) ([]models.Appointment, error) {
+	if e.Clock == "" && e.DID == 0 && e.PHealth == nil && e.ProblemHint == nil {
+		return nil, ErrNothingToEdit
+	}
+	var clock time.Time
+	if e.Clock != "" {
+		var err error
+		if clock, err = appointments.ParseClock(e.Clock); err != nil {
+			return nil, err
+		}
+	}
+
+	edited := []models.Appointment{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		series, rows, err := remainder(tx, seriesID, fromID)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		did := series.DID
+		if e.DID != 0 {
+			did = e.DID
+		}
+		doctor, err := loadDoctor(tx, did)
+		if err != nil {
+			return err
+		}
+		schedule, err := loadSchedule(tx, did, rows[0].StartsAt, rows[len(rows)-1].StartsAt)
+		if err != nil {
+			return err
+		}
+
+		var skipped []Skipped
+		for _, row := range rows {
+			a := &row
+			if e.Clock != "" || e.DID != 0 {
+				start := clinic.In(row.StartsAt)
+				if e.Clock != "" {
+					start = clinic.At(start, clock)
+				}
+				if !fits(doctor, schedule, start, start.Add(time.Duration(row.DurationMinutes)*time.Minute)) {
+					skipped = append(skipped, Skipped{StartsAt: start, Reason: OutsideSchedule})
+					continue
+				}
+				var conflict *appointments.ConflictError
+				a, err = appointments.Reschedule(tx, row.ID, start, did, e.Reason, actor)
+				switch {
+				case errors.As(err, &conflict):
+					skipped = append(skipped, Skipped{StartsAt: start, Reason: DoubleBooked, Conflicts: conflict.Conflicts})
+					continue
+				case errors.Is(err, appointments.ErrInPast):
+					skipped = append(skipped, Skipped{StartsAt: start, Reason: InPast})
+					continue
+				case errors.Is(err, appointments.ErrUnchanged):
+					a = &row
+				case err != nil:
+					return err
+				}
+			}
+			if e.PHealth != nil || e.ProblemHint != nil {
+				details := appointments.Details{PHealth: a.PHealth, ProblemHint: a.ProblemHint}
+				if e.PHealth != nil {
+					details.PHealth = *e.PHealth
+				}
+				if e.ProblemHint != nil {
+					details.ProblemHint = *e.ProblemHint
+				}
+				if a, err = appointments.Update(tx, row.ID, details, actor); err != nil {
+					return err
+				}
+			}
+			edited = append(edited, *a)
+		}
+		if len(skipped) > 0 {
+			return &OccurrenceError{Skipped: skipped}
+		}
+
+		if e.DID != 0 {
+			series.DID = e.DID
+		}
+		if e.PHealth != nil {
+			series.PHealth = *e.PHealth
+		}
+		if e.ProblemHint != nil {
+			series.ProblemHint = *e.ProblemHint
+		}
+		return tx.Model(series).Updates(map[string]interface{}{
+			"d_id":         series.DID,
+			"p_health":     series.PHealth,
+			"problem_hint": series.ProblemHint,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return edited, nil
+}
@@ -0,0 +1,232 @@
+// Package schedules turns doctors' weekly windows, date overrides and leave
+// into bookable slots.
+package schedules
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"gorm.io/gorm"
+)
+
+// MaxRangeDays caps one availability query.
+const MaxRangeDays = 62
+
+var (
+	ErrRangeTooLong = errors.New("availability range is too long")
+	ErrRangeOrder   = errors.New("to must not be before from")
+)
+
+// Slot is a free interval of SlotMinutes starting at StartsAt.
+type Slot struct {
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	SlotMinutes int       `json:"slot_minutes"`
+}
+
+// window is a working interval on one date.
+type window struct {
+	start, end time.Time
+	slot       time.Duration
+}
+
+// Schedule is everything that shapes a doctor's working days.
+type Schedule struct {
+	Weekly    []models.DoctorSchedule         `json:"weekly"`
+	Overrides []models.DoctorScheduleOverride `json:"overrides"`
+	Leave     []models.DoctorLeave            `json:"leave"`
+}
+
+// Load returns the doctor's weekly windows, and the overrides and leave
+// touching the days of from..to in the clinic.
+func Load(db *gorm.DB, did int, from, to time.Time) (*Schedule, error) {
+	from, to = dateOf(clinic.In(from)), dateOf(clinic.In(to))
+	s := &Schedule{}
+	if err := db.Where("d_id = ?", did).Order("weekday, start_time").Find(&s.Weekly).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("d_id = ? AND date BETWEEN ? AND ?", did, from, to).
+		Order("date, start_time").Find(&s.Overrides).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("d_id = ? AND starts_on <= ? AND ends_on >= ?", did, to, from).
+		Order("starts_on").Find(&s.Leave).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReplaceWeekly swaps the doctor's weekly windows for windows.
+func ReplaceWeekly(db *gorm.DB, did int, windows []models.DoctorSchedule) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("d_id = ?", did).Delete(&models.DoctorSchedule{}).Error; err != nil {
+			return err
+		}
+		if len(windows) == 0 {
+			return nil
+		}
+		for i := range windows {
+			windows[i].ID = 0
+			windows[i].DID = did
+			if windows[i].SlotMinutes == 0 {
+				windows[i].SlotMinutes = appointments.DefaultDurationMinutes
+			}
+		}
+		return tx.Create(&windows).Error
+	})
+}
+
+// onLeave reports whether date falls in any leave.
+func (s *Schedule) onLeave(date time.Time) bool {
+	for _, l := range s.Leave {
+		if !date.Before(dateOf(l.StartsOn)) && !date.After(dateOf(l.EndsOn)) {
+			return true
+		}
+	}
+	return false
+}
+
+// windows returns the working intervals on date: the overrides for the
+// date when there are any, otherwise the weekly windows valid on it.
+func (s *Schedule) windows(date time.Time) []window {
+	if s.onLeave(date) {
+		return nil
+	}
+
+	var out []window
+	overridden := false
+	for _, o := range s.Overrides {
+		if !dateOf(o.Date).Equal(date) {
+			continue
+		}
+		overridden = true
+		if o.Closed {
+			return nil
+		}
+		if w, ok := newWindow(date, o.StartTime, o.EndTime, o.SlotMinutes); ok {
+			out = append(out, w)
+		}
+	}
+	if overridden {
+		return out
+	}
+
+	for _, ws := range s.Weekly {
+		if ws.Weekday != int(date.Weekday()) {
+			continue
+		}
+		if ws.ValidFrom != nil && date.Before(dateOf(*ws.ValidFrom)) {
+			continue
+		}
+		if ws.ValidTo != nil && date.After(dateOf(*ws.ValidTo)) {
+			continue
+		}
+		if w, ok := newWindow(date, ws.StartTime, ws.EndTime, ws.SlotMinutes); ok {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// Covers reports whether start..end lies inside one working window of its
+// date in the clinic. The schedule must have been loaded for that date.
+func (s *Schedule) Covers(start, end time.Time) bool {
+	for _, w := range s.windows(dateOf(clinic.In(start))) {
+		if !start.Before(w.start) && !end.After(w.end) {
+			return true
+		}
+	}
+	return false
+}
+
+func newWindow(date time.Time, start, end string, slotMinutes int) (window, bool) {
+	s, err1 := appointments.ParseClock(start)
+	e, err2 := appointments.ParseClock(end)
+	if err1 != nil || err2 != nil {
+		return window{}, false
+	}
+	if slotMinutes <= 0 {
+		slotMinutes = appointments.DefaultDurationMinutes
+	}
+	return window{
+		start: clinic.At(date, s),
+		end:   clinic.At(date, e),
+		slot:  time.Duration(slotMinutes) * time.Minute,
+	}, true
+}
+
+// dateOf is t's calendar date as midnight in the clinic, so dates from date
+// columns and from instants compare equal.
+func dateOf(t time.Time) time.Time {
+	return clinic.Midnight(t)
+}
+
+type busy struct {
+	StartsAt time.Time `gorm:"column:starts_at"`
+	EndsAt   time.Time `gorm:"column:ends_at"`
+}
+
+// Availability returns the doctor's free slots on the from..to dates, both
+// inclusive and taken as calendar dates in the clinic, soonest first. Slots
+// that have already started and slots overlapping an appointment that holds
+// its slot are left out. Inactive doctors have no availability.
+func Availability(db *gorm.DB, doctor models.Doctor, from, to time.Time) ([]Slot, error) {
+	from, to = dateOf(from), dateOf(to)
+	if to.Before(from) {
+		return nil, ErrRangeOrder
+	}
+	if to.Sub(from) >= MaxRangeDays*24*time.Hour {
+		return nil, ErrRangeTooLong
+	}
+	slots := []Slot{}
+	if strings.EqualFold(doctor.DStatus, "inactive") {
+		return slots, nil
+	}
+
+	did := int(doctor.DID)
+	schedule, err := Load(db, did, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var booked []busy
+	err = db.Table("appointments").
+		Select(appointments.StartSQL+" AS starts_at, "+appointments.EndSQL+" AS ends_at").
+		Where("d_id = ? AND appo_status IN ?", did, appointments.BlockingStatuses).
+		Where(appointments.StartSQL+" < ? AND "+appointments.EndSQL+" > ?", to.AddDate(0, 0, 1), from).
+		Order("starts_at").
+		Scan(&booked).Error
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		windows := schedule.windows(date)
+		sort.Slice(windows, func(i, j int) bool { return windows[i].start.Before(windows[j].start) })
+		for _, w := range windows {
+			for start := w.start; !start.Add(w.slot).After(w.end); start = start.Add(w.slot) {
+				end := start.Add(w.slot)
+				if start.Before(now) || overlaps(booked, start, end) {
+					continue
+				}
+				slots = append(slots, Slot{StartsAt: start, EndsAt: end, SlotMinutes: int(w.slot / time.Minute)})
+			}
+		}
+	}
+	return slots, nil
+}
+
+func overlaps(booked []busy, start, end time.Time) bool {
+	for _, b := range booked {
+		if b.StartsAt.Before(end) && b.EndsAt.After(start) {
+			return true
+		}
+	}
+	return false
+}
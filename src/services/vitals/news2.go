@@ -0,0 +1,151 @@
+package vitals
+
+import (
+	"strings"
+
+	models "github.com/PragaL15/med_admin_backend/src/model"
+)
+
+// NEWS2 scores v using the Royal College of Physicians NEWS2 chart. ok is
+// false when any of the seven parameters is missing, in which case no score
+// is given rather than a misleadingly low one.
+func NEWS2(v models.VitalSign) (score int, risk string, ok bool) {
+	if v.RespRate == nil || v.SpO2 == nil || v.SystolicBP == nil || v.HeartRate == nil ||
+		v.Temperature == nil || v.Consciousness == "" {
+		return 0, "", false
+	}
+
+	parts := []int{
+		respRateScore(*v.RespRate),
+		spo2Score(*v.SpO2, v.SpO2Scale, v.OnOxygen),
+		systolicScore(*v.SystolicBP),
+		heartRateScore(*v.HeartRate),
+		temperatureScore(*v.Temperature),
+		consciousnessScore(v.Consciousness),
+	}
+	if v.OnOxygen {
+		parts = append(parts, 2)
+	}
+
+	redFlag := false
+	for _, p := range parts {
+		score += p
+		if p == 3 {
+			redFlag = true
+		}
+	}
+
+	switch {
+	case score >= 7:
+		risk = models.NEWS2RiskHigh
+	case score >= 5:
+		risk = models.NEWS2RiskMedium
+	case redFlag:
+		risk = models.NEWS2RiskLowMedium
+	default:
+		risk = models.NEWS2RiskLow
+	}
+	return score, risk, true
+}
+
+func respRateScore(rr int) int {
+	switch {
+	case rr <= 8:
+		return 3
+	case rr <= 11:
+		return 1
+	case rr <= 20:
+		return 0
+	case rr <= 24:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func spo2Score(spo2, scale int, onOxygen bool) int {
+	if scale != 2 {
+		switch {
+		case spo2 <= 91:
+			return 3
+		case spo2 <= 93:
+			return 2
+		case spo2 <= 95:
+			return 1
+		default:
+			return 0
+		}
+	}
+	// Scale 2: 88-92% is the target range; higher saturations only score
+	// when they are achieved on oxygen.
+	switch {
+	case spo2 <= 83:
+		return 3
+	case spo2 <= 85:
+		return 2
+	case spo2 <= 87:
+		return 1
+	case spo2 <= 92 || !onOxygen:
+		return 0
+	case spo2 <= 94:
+		return 1
+	case spo2 <= 96:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func systolicScore(sbp int) int {
+	switch {
+	case sbp <= 90:
+		return 3
+	case sbp <= 100:
+		return 2
+	case sbp <= 110:
+		return 1
+	case sbp <= 219:
+		return 0
+	default:
+		return 3
+	}
+}
+
+func heartRateScore(hr int) int {
+	switch {
+	case hr <= 40:
+		return 3
+	case hr <= 50:
+		return 1
+	case hr <= 90:
+		return 0
+	case hr <= 110:
+		return 1
+	case hr <= 130:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func temperatureScore(t float64) int {
+	switch {
+	case t <= 35.0:
+		return 3
+	case t <= 36.0:
+		return 1
+	case t <= 38.0:
+		return 0
+	case t <= 39.0:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func consciousnessScore(level string) int {
+	if strings.EqualFold(level, "alert") {
+		return 0
+	}
+	return 3
+}
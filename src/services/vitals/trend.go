@@ -0,0 +1,49 @@
+package vitals
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Buckets accepted by Trend.
+var Buckets = []string{"hour", "day", "week"}
+
+// TrendPoint summarises the observations in one time bucket. Averages are
+// nil when nothing of that kind was measured in the bucket.
+type TrendPoint struct {
+	Bucket      time.Time `gorm:"column:bucket" json:"bucket"`
+	Readings    int       `gorm:"column:readings" json:"readings"`
+	RespRate    *float64  `gorm:"column:resp_rate" json:"resp_rate"`
+	SpO2        *float64  `gorm:"column:spo2" json:"spo2"`
+	SystolicBP  *float64  `gorm:"column:systolic_bp" json:"systolic_bp"`
+	DiastolicBP *float64  `gorm:"column:diastolic_bp" json:"diastolic_bp"`
+	HeartRate   *float64  `gorm:"column:heart_rate" json:"heart_rate"`
+	Temperature *float64  `gorm:"column:temperature" json:"temperature"`
+	MaxNEWS2    *int      `gorm:"column:max_news2" json:"max_news2"`
+	LatestNEWS2 *int      `gorm:"column:latest_news2" json:"latest_news2"`
+}
+
+// Trend aggregates a patient's observations in [from, to) into buckets,
+// oldest first. bucket must be one of Buckets.
+func Trend(db *gorm.DB, pid uint, admissionID *int, from, to time.Time, bucket string) ([]TrendPoint, error) {
+	q := db.Table("vital_signs").
+		Select(`date_trunc(?, recorded_at) AS bucket,
+			count(*) AS readings,
+			round(avg(resp_rate)::numeric, 1) AS resp_rate,
+			round(avg(spo2)::numeric, 1) AS spo2,
+			round(avg(systolic_bp)::numeric, 1) AS systolic_bp,
+			round(avg(diastolic_bp)::numeric, 1) AS diastolic_bp,
+			round(avg(heart_rate)::numeric, 1) AS heart_rate,
+			round(avg(temperature)::numeric, 1) AS temperature,
+			max(news2) AS max_news2,
+			(array_agg(news2 ORDER BY recorded_at DESC) FILTER (WHERE news2 IS NOT NULL))[1] AS latest_news2`, bucket).
+		Where("p_id = ? AND recorded_at >= ? AND recorded_at < ?", pid, from, to)
+	if admissionID != nil {
+		q = q.Where("admission_id = ?", *admissionID)
+	}
+
+	points := []TrendPoint{}
+	err := q.Group("1").Order("1").Scan(&points).Error
+	return points, err
+}
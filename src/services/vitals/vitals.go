@@ -0,0 +1,126 @@
+package vitals
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/events"
+	"github.com/PragaL15/med_admin_backend/src/metrics"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultAlertThreshold = 5
+
+// EventNEWS2Alert is published with an Alert payload when a patient's NEWS2
+// score reaches the alert threshold from below.
+const EventNEWS2Alert = "vitals.news2_alert"
+
+var ErrAdmissionMismatch = errors.New("admission does not belong to this patient")
+
+// Alert is the payload of EventNEWS2Alert.
+type Alert struct {
+	PID         uint      `json:"p_id"`
+	AdmissionID *int      `json:"admission_id,omitempty"`
+	VitalID     int       `json:"vital_id"`
+	Score       int       `json:"score"`
+	Previous    *int      `json:"previous,omitempty"`
+	Risk        string    `json:"risk"`
+	Threshold   int       `json:"threshold"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+// AlertThreshold is the NEWS2 score that raises an alert. The default of 5
+// is the NEWS2 key threshold for an urgent clinical response. Override with
+// NEWS2_ALERT_THRESHOLD.
+func AlertThreshold() int {
+	if v := os.Getenv("NEWS2_ALERT_THRESHOLD"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 20 {
+			return n
+		}
+		log.Printf("Ignoring invalid NEWS2_ALERT_THRESHOLD %q", v)
+	}
+	return defaultAlertThreshold
+}
+
+// Record scores and stores v. When the score crosses the alert threshold
+// compared with the previous scored observation in the same series (the
+// admission when given, otherwise the patient), EventNEWS2Alert is published
+// after the row is committed.
+func Record(db *gorm.DB, v *models.VitalSign) error {
+	if score, risk, ok := NEWS2(*v); ok {
+		v.NEWS2 = &score
+		v.NEWS2Risk = risk
+	} else {
+		v.NEWS2 = nil
+		v.NEWS2Risk = ""
+	}
+	if v.SpO2Scale == 0 {
+		v.SpO2Scale = 1
+	}
+
+	var previous *int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if v.AdmissionID != nil {
+			var count int64
+			if err := tx.Table("admitted").Where("id = ? AND p_id = ?", *v.AdmissionID, v.PID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrAdmissionMismatch
+			}
+		}
+
+		// Lock the patient row so concurrent entries for the same patient
+		// compare against each other rather than both against the same
+		// earlier score.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("p_id").Where("p_id = ?", v.PID).First(&models.Patient{}).Error; err != nil {
+			return err
+		}
+
+		var last models.VitalSign
+		q := series(tx, v).Where("news2 IS NOT NULL AND recorded_at <= ?", v.RecordedAt).
+			Order("recorded_at DESC, id DESC").Limit(1).Find(&last)
+		if q.Error != nil {
+			return q.Error
+		}
+		if q.RowsAffected > 0 {
+			previous = last.NEWS2
+		}
+		return tx.Create(v).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	threshold := AlertThreshold()
+	if v.NEWS2 != nil && *v.NEWS2 >= threshold && (previous == nil || *previous < threshold) {
+		alert := Alert{
+			PID:         v.PID,
+			AdmissionID: v.AdmissionID,
+			VitalID:     v.ID,
+			Score:       *v.NEWS2,
+			Previous:    previous,
+			Risk:        v.NEWS2Risk,
+			Threshold:   threshold,
+			RecordedAt:  v.RecordedAt,
+		}
+		log.Printf("NEWS2 alert: p_id %d scored %d (%s), threshold %d", v.PID, alert.Score, alert.Risk, threshold)
+		metrics.NEWS2Alerts.WithLabelValues(alert.Risk).Inc()
+		events.Publish(db.Statement.Context, events.Event{Type: EventNEWS2Alert, Payload: alert})
+	}
+	return nil
+}
+
+// series scopes a query to the observation series v belongs to.
+func series(db *gorm.DB, v *models.VitalSign) *gorm.DB {
+	if v.AdmissionID != nil {
+		return db.Where("admission_id = ?", *v.AdmissionID)
+	}
+	return db.Where("p_id = ?", v.PID)
+}
@@ -0,0 +1,349 @@
+// Package waitlist keeps patients waiting for a doctor and offers them
+// freed slots. A cancellation offers the slot to the most urgent waiting
+// patient whose date range covers it; the slot is held for them until the
+// offer expires, then offered to the next patient.
+package waitlist
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/clinic"
+	"github.com/PragaL15/med_admin_backend/src/events"
+	"github.com/PragaL15/med_admin_backend/src/metrics"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/services/appointments"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultHoldMinutes = 30
+	expiryInterval     = time.Minute
+)
+
+// EventOffered is published after a slot is offered. Payload is the
+// models.WaitlistOffer.
+const EventOffered = "waitlist.offered"
+
+var (
+	ErrNotFound      = errors.New("waitlist entry not found")
+	ErrOfferNotFound = errors.New("offer not found")
+	ErrOfferClosed   = errors.New("offer has already been answered")
+	ErrOfferExpired  = errors.New("offer has expired")
+	ErrNotPermitted  = errors.New("not permitted to answer this offer")
+)
+
+// HoldDuration is how long an offered slot is held. Override with
+// WAITLIST_HOLD_MINUTES.
+func HoldDuration() time.Duration {
+	if v := os.Getenv("WAITLIST_HOLD_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 24*60 {
+			return time.Duration(n) * time.Minute
+		}
+		log.Printf("Ignoring invalid WAITLIST_HOLD_MINUTES %q", v)
+	}
+	return defaultHoldMinutes * time.Minute
+}
+
+// Register offers slots freed by cancellations and starts expiring
+// unanswered offers in the background until ctx is done.
+func Register(ctx context.Context, db *gorm.DB) {
+	events.Subscribe(appointments.EventCancelled, func(ctx context.Context, e events.Event) {
+		c, ok := e.Payload.(appointments.Cancelled)
+		if !ok {
+			return
+		}
+		if _, err := OfferCancelled(db.WithContext(ctx), c); err != nil {
+			log.Printf("Error offering slot of cancelled appointment %d: %v", c.AppointmentID, err)
+		}
+	})
+
+	go func() {
+		ticker := time.NewTicker(expiryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := ExpireOffers(db.WithContext(ctx)); err != nil {
+					log.Printf("Error expiring waitlist offers: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Add puts a patient on a doctor's waitlist.
+func Add(db *gorm.DB, entry *models.WaitlistEntry, userID int) error {
+	if err := appointments.CheckParticipants(db, entry.PID, entry.DID); err != nil {
+		return err
+	}
+	entry.EarliestDate = dateOf(entry.EarliestDate)
+	entry.LatestDate = dateOf(entry.LatestDate)
+	if entry.Priority == 0 {
+		entry.Priority = 3
+	}
+	if entry.DurationMinutes == 0 {
+		entry.DurationMinutes = appointments.DefaultDurationMinutes
+	}
+	entry.ID = 0
+	entry.Status = models.WaitlistWaiting
+	entry.CreatedBy = userID
+	return db.Create(entry).Error
+}
+
+// Remove takes a waiting entry off the list. Entries with a pending offer
+// keep it until it is answered or expires.
+func Remove(db *gorm.DB, id int) error {
+	result := db.Model(&models.WaitlistEntry{}).
+		Where("id = ? AND status IN ?", id, []string{models.WaitlistWaiting, models.WaitlistOffered}).
+		Update("status", models.WaitlistRemoved)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// slot is a freed interval to offer.
+type slot struct {
+	did         int
+	start       time.Time
+	minutes     int
+	sourceAppID *int
+}
+
+// OfferCancelled offers the slot of a cancelled appointment. It returns nil
+// without error when the appointment is not cancelled after all, the slot
+// has passed or nobody is waiting.
+func OfferCancelled(db *gorm.DB, c appointments.Cancelled) (*models.WaitlistOffer, error) {
+	var status string
+	if err := db.Model(&models.Appointment{}).Where("id = ?", c.AppointmentID).Pluck("appo_status", &status).Error; err != nil {
+		return nil, err
+	}
+	if status != models.StatusCancelled {
+		return nil, nil
+	}
+	return offer(db, slot{did: c.DID, start: c.StartsAt, minutes: c.DurationMinutes, sourceAppID: &c.AppointmentID})
+}
+
+// offer holds s for the next eligible waiting patient: the doctor's
+// most urgent, longest waiting entry whose dates cover the slot, that fits
+// in it and has not been offered this slot before.
+func offer(db *gorm.DB, s slot) (*models.WaitlistOffer, error) {
+	if !s.start.After(time.Now()) {
+		return nil, nil
+	}
+	var made *models.WaitlistOffer
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var entry models.WaitlistEntry
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("d_id = ? AND status = ?", s.did, models.WaitlistWaiting).
+			Where("earliest_date <= ? AND latest_date >= ?", clinic.Midnight(clinic.In(s.start)), clinic.Midnight(clinic.In(s.start))).
+			Where("duration_minutes <= ?", s.minutes).
+			Where(`NOT EXISTS (SELECT 1 FROM waitlist_offers o
+				WHERE o.entry_id = waitlist_entries.id AND o.d_id = ? AND o.starts_at = ?)`, s.did, s.start).
+			Order("priority, created_at, id").
+			Take(&entry).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		made = &models.WaitlistOffer{
+			EntryID:             entry.ID,
+			PID:                 entry.PID,
+			DID:                 s.did,
+			StartsAt:            s.start,
+			DurationMinutes:     entry.DurationMinutes,
+			Status:              models.OfferPending,
+			ExpiresAt:           time.Now().Add(HoldDuration()),
+			SourceAppointmentID: s.sourceAppID,
+		}
+		if err := tx.Create(made).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entry).Update("status", models.WaitlistOffered).Error
+	})
+	if err != nil || made == nil {
+		return nil, err
+	}
+	events.Publish(db.Statement.Context, events.Event{Type: EventOffered, Payload: *made})
+	return made, nil
+}
+
+// lockOffer loads a pending offer FOR UPDATE.
+func lockOffer(tx *gorm.DB, id int) (*models.WaitlistOffer, error) {
+	var o models.WaitlistOffer
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Take(&o).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOfferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.Status == models.OfferExpired || (o.Status == models.OfferPending && !o.ExpiresAt.After(time.Now())) {
+		return nil, ErrOfferExpired
+	}
+	if o.Status != models.OfferPending {
+		return nil, ErrOfferClosed
+	}
+	return &o, nil
+}
+
+// mayAnswer allows staff and admins, the patient offered the slot and the
+// doctor whose slot it is.
+func mayAnswer(actor appointments.Actor, o *models.WaitlistOffer) bool {
+	switch actor.Role {
+	case appointments.RolePatient:
+		return actor.PID == o.PID
+	case appointments.RoleDoctor:
+		return actor.DID == o.DID
+	default:
+		return true
+	}
+}
+
+// Accept books the offered slot for the waitlisted patient.
+func Accept(db *gorm.DB, id int, actor appointments.Actor) (*models.WaitlistOffer, *models.Appointment, error) {
+	var o *models.WaitlistOffer
+	var a *models.Appointment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if o, err = lockOffer(tx, id); err != nil {
+			return err
+		}
+		if !mayAnswer(actor, o) {
+			return ErrNotPermitted
+		}
+
+		// Release the hold before booking so it does not conflict with
+		// the booking it was held for.
+		now := time.Now()
+		o.Status = models.OfferAccepted
+		o.RespondedAt = &now
+		o.RespondedBy = &actor.UserID
+		if err := tx.Model(o).Updates(map[string]interface{}{
+			"status":       o.Status,
+			"responded_at": o.RespondedAt,
+			"responded_by": o.RespondedBy,
+		}).Error; err != nil {
+			return err
+		}
+
+		var entry models.WaitlistEntry
+		if err := tx.Where("id = ?", o.EntryID).Take(&entry).Error; err != nil {
+			return err
+		}
+		a = &models.Appointment{
+			PID:             o.PID,
+			DID:             o.DID,
+			ProblemHint:     entry.Notes,
+			DurationMinutes: o.DurationMinutes,
+		}
+		appointments.SetStart(a, o.StartsAt)
+		if err := appointments.Create(tx, a, actor); err != nil {
+			return err
+		}
+
+		o.AppointmentID = &a.ID
+		if err := tx.Model(o).Update("appointment_id", a.ID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entry).Update("status", models.WaitlistBooked).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	metrics.AppointmentsCreated.Inc()
+	return o, a, nil
+}
+
+// Decline returns the patient to the waitlist and offers the slot to the
+// next patient.
+func Decline(db *gorm.DB, id int, actor appointments.Actor) (*models.WaitlistOffer, error) {
+	var o *models.WaitlistOffer
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if o, err = lockOffer(tx, id); err != nil {
+			return err
+		}
+		if !mayAnswer(actor, o) {
+			return ErrNotPermitted
+		}
+		now := time.Now()
+		o.Status = models.OfferDeclined
+		o.RespondedAt = &now
+		o.RespondedBy = &actor.UserID
+		if err := tx.Model(o).Updates(map[string]interface{}{
+			"status":       o.Status,
+			"responded_at": o.RespondedAt,
+			"responded_by": o.RespondedBy,
+		}).Error; err != nil {
+			return err
+		}
+		return requeue(tx, o.EntryID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := offer(db, slot{did: o.DID, start: o.StartsAt, minutes: o.DurationMinutes, sourceAppID: o.SourceAppointmentID}); err != nil {
+		log.Printf("Error re-offering slot of declined offer %d: %v", o.ID, err)
+	}
+	return o, nil
+}
+
+// requeue puts an entry whose offer lapsed back in line, keeping its
+// original place.
+func requeue(tx *gorm.DB, entryID int) error {
+	return tx.Model(&models.WaitlistEntry{}).
+		Where("id = ? AND status = ?", entryID, models.WaitlistOffered).
+		Update("status", models.WaitlistWaiting).Error
+}
+
+// ExpireOffers lapses pending offers past their hold and offers each slot
+// to the next patient.
+func ExpireOffers(db *gorm.DB) error {
+	var expired []models.WaitlistOffer
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expires_at <= ?", models.OfferPending, time.Now()).
+			Find(&expired).Error
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+		ids := make([]int, len(expired))
+		for i, o := range expired {
+			ids[i] = o.ID
+			if err := requeue(tx, o.EntryID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.WaitlistOffer{}).Where("id IN ?", ids).Update("status", models.OfferExpired).Error
+	})
+	if err != nil {
+		return err
+	}
+	for _, o := range expired {
+		if _, err := offer(db, slot{did: o.DID, start: o.StartsAt, minutes: o.DurationMinutes, sourceAppID: o.SourceAppointmentID}); err != nil {
+			log.Printf("Error re-offering slot of expired offer %d: %v", o.ID, err)
+		}
+	}
+	return nil
+}
+
+// dateOf is the calendar date d, such as a parsed YYYY-MM-DD, as midnight
+// in the clinic.
+func dateOf(d time.Time) time.Time {
+	return clinic.Midnight(d)
+}
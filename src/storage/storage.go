@@ -0,0 +1,47 @@
+// Package storage keeps uploaded files outside the database. Store has a
+// local filesystem implementation for development and single-node installs
+// and an S3 compatible one (AWS S3, MinIO) for everything else.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Store saves and returns objects by key. Keys are slash separated paths
+// chosen by the caller and never derived from user input.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New builds the Store selected by DOCUMENT_STORAGE: "local" (the default)
+// writes under DOCUMENT_STORAGE_DIR, "s3" uses the S3_* settings.
+func New(ctx context.Context) (Store, error) {
+	switch kind := strings.ToLower(os.Getenv("DOCUMENT_STORAGE")); kind {
+	case "", "local":
+		dir := os.Getenv("DOCUMENT_STORAGE_DIR")
+		if dir == "" {
+			dir = "data/documents"
+		}
+		return NewLocal(dir)
+	case "s3":
+		return NewS3(ctx, S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    !strings.EqualFold(os.Getenv("S3_USE_SSL"), "false"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown DOCUMENT_STORAGE %q", kind)
+	}
+}
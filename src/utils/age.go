@@ -0,0 +1,12 @@
+package utils
+
+import "time"
+
+// AgeAt returns the age in completed years of someone born on dob at time t.
+func AgeAt(dob, t time.Time) int {
+	age := t.Year() - dob.Year()
+	if t.Month() < dob.Month() || (t.Month() == dob.Month() && t.Day() < dob.Day()) {
+		age--
+	}
+	return age
+}
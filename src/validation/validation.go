@@ -0,0 +1,269 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON name so errors line up with the payload.
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	must(v.RegisterValidation("notfuture", notFuture))
+	for tag, values := range enums {
+		must(v.RegisterValidation(tag, oneOfFold(values)))
+	}
+	v.RegisterStructValidation(patientAgeMatchesDOB, models.Patient{})
+	v.RegisterStructValidation(problemDates, models.PatientProblem{})
+	v.RegisterStructValidation(scheduleHours, models.DoctorSchedule{})
+	v.RegisterStructValidation(overrideHours, models.DoctorScheduleOverride{})
+	v.RegisterStructValidation(leaveDates, models.DoctorLeave{})
+	v.RegisterStructValidation(waitlistDates, models.WaitlistEntry{})
+
+	return v
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// enums maps a validate tag to the values it accepts. The value lists live in
+// the models package next to the columns they constrain.
+var enums = map[string][]string{
+	"patient_status":       models.PatientStatuses,
+	"gender":               models.Genders,
+	"patient_mode":         models.PatientModes,
+	"doctor_status":        models.DoctorStatuses,
+	"appointment_status":   models.AppointmentStatuses,
+	"identifier_type":      models.IdentifierTypes,
+	"consent_type":         models.ConsentTypes,
+	"contact_relationship": models.ContactRelationships,
+	"code_system":          models.CodeSystems,
+	"allergy_category":     models.AllergyCategories,
+	"allergy_severity":     models.AllergySeverities,
+	"allergy_status":       models.AllergyStatuses,
+	"problem_status":       models.ProblemStatuses,
+	"consciousness_level":  models.ConsciousnessLevels,
+	"consent_status":       models.ConsentStatuses,
+	"document_category":    models.DocumentCategories,
+	"leave_kind":           models.LeaveKinds,
+}
+
+func oneOfFold(values []string) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		s := fl.Field().String()
+		for _, v := range values {
+			if strings.EqualFold(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func notFuture(fl validator.FieldLevel) bool {
+	t, ok := fl.Field().Interface().(time.Time)
+	if !ok {
+		return false
+	}
+	return !t.After(time.Now())
+}
+
+func patientAgeMatchesDOB(sl validator.StructLevel) {
+	p := sl.Current().Interface().(models.Patient)
+	if p.DOB.IsZero() {
+		return
+	}
+	if p.Age != utils.AgeAt(p.DOB, time.Now()) {
+		sl.ReportError(p.Age, "age", "Age", "age_dob", "")
+	}
+}
+
+// problemDates requires a resolution date on resolved problems and keeps it
+// on or after the onset date.
+func problemDates(sl validator.StructLevel) {
+	p := sl.Current().Interface().(models.PatientProblem)
+	if strings.EqualFold(p.Status, "resolved") && p.ResolvedDate == nil {
+		sl.ReportError(p.ResolvedDate, "resolved_date", "ResolvedDate", "required_if_resolved", "")
+	}
+	if p.OnsetDate != nil && p.ResolvedDate != nil && p.ResolvedDate.Before(*p.OnsetDate) {
+		sl.ReportError(p.ResolvedDate, "resolved_date", "ResolvedDate", "after_onset", "")
+	}
+}
+
+// scheduleHours keeps a weekly window's end after its start and its
+// validity dates in order. Times that fail their datetime tag are left to
+// that tag.
+func scheduleHours(sl validator.StructLevel) {
+	s := sl.Current().Interface().(models.DoctorSchedule)
+	if !clockBefore(s.StartTime, s.EndTime) {
+		sl.ReportError(s.EndTime, "end_time", "EndTime", "after_start_time", "")
+	}
+	if s.ValidFrom != nil && s.ValidTo != nil && s.ValidTo.Before(*s.ValidFrom) {
+		sl.ReportError(s.ValidTo, "valid_to", "ValidTo", "after_valid_from", "")
+	}
+}
+
+func overrideHours(sl validator.StructLevel) {
+	o := sl.Current().Interface().(models.DoctorScheduleOverride)
+	if !o.Closed && !clockBefore(o.StartTime, o.EndTime) {
+		sl.ReportError(o.EndTime, "end_time", "EndTime", "after_start_time", "")
+	}
+}
+
+func leaveDates(sl validator.StructLevel) {
+	l := sl.Current().Interface().(models.DoctorLeave)
+	if l.EndsOn.Before(l.StartsOn) {
+		sl.ReportError(l.EndsOn, "ends_on", "EndsOn", "after_starts_on", "")
+	}
+}
+
+func waitlistDates(sl validator.StructLevel) {
+	e := sl.Current().Interface().(models.WaitlistEntry)
+	if e.LatestDate.Before(e.EarliestDate) {
+		sl.ReportError(e.LatestDate, "latest_date", "LatestDate", "after_earliest_date", "")
+	}
+}
+
+// clockBefore compares two "HH:MM" times. It is true when either does not
+// parse, so the datetime tag reports the bad value instead.
+func clockBefore(start, end string) bool {
+	s, err1 := time.Parse("15:04", start)
+	e, err2 := time.Parse("15:04", end)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	return s.Before(e)
+}
+
+// Struct validates v against its validate tags and returns every violation
+// as a single validation_failed error, or nil.
+func Struct(v interface{}) error {
+	return toAPIError(validate.Struct(v))
+}
+
+// StructExcept is Struct with the named fields (Go field names) skipped, for
+// partial updates that do not carry every column.
+func StructExcept(v interface{}, fields ...string) error {
+	return toAPIError(validate.StructExcept(v, fields...))
+}
+
+// Join combines the results of several validation passes into one
+// validation_failed error carrying every field error, so a client sees all
+// problems with a request at once. Errors that are not validation errors are
+// returned as they are.
+func Join(errs ...error) error {
+	var fields []apierror.FieldError
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var apiErr *apierror.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != apierror.CodeValidationFailed {
+			return err
+		}
+		fields = append(fields, apiErr.Fields...)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return apierror.Validation(fields...)
+}
+
+func toAPIError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return apierror.Wrap(apierror.CodeInternal, "Failed to validate request", err)
+	}
+
+	fields := make([]apierror.FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, apierror.FieldError{
+			Field:   fieldPath(fe),
+			Code:    fe.Tag(),
+			Message: message(fe),
+		})
+	}
+	return apierror.Validation(fields...)
+}
+
+// fieldPath drops the root struct name from the namespace, so
+// "Patient.emergency_contact.phone" becomes "emergency_contact.phone".
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func message(fe validator.FieldError) string {
+	if values, ok := enums[fe.Tag()]; ok {
+		return "must be one of: " + strings.Join(values, ", ")
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "e164":
+		return "must be an E.164 phone number such as +919876543210"
+	case "notfuture":
+		return "must not be in the future"
+	case "age_dob":
+		return "does not match date of birth"
+	case "required_if_resolved":
+		return "is required when status is resolved"
+	case "after_onset":
+		return "must not be before onset_date"
+	case "after_start_time":
+		return "must be after start_time"
+	case "after_valid_from":
+		return "must not be before valid_from"
+	case "after_starts_on":
+		return "must not be before starts_on"
+	case "after_earliest_date":
+		return "must not be before earliest_date"
+	case "required_unless":
+		return "is required unless the day is closed"
+	case "required_with":
+		return "is required when " + fe.Param() + " is set"
+	case "required_without":
+		return "is required when " + fe.Param() + " is not set"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "datetime":
+		return "must be formatted as " + fe.Param()
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "gtfield":
+		return "must be after " + fe.Param()
+	default:
+		return "failed " + fe.Tag() + " validation"
+	}
+}
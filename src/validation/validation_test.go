@@ -0,0 +1,168 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/PragaL15/med_admin_backend/src/apierror"
+	models "github.com/PragaL15/med_admin_backend/src/model"
+	"github.com/PragaL15/med_admin_backend/src/utils"
+)
+
+// failures returns the "field:code" pairs of a validation error, sorted.
+func failures(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != apierror.CodeValidationFailed {
+		t.Fatalf("err = %v, want a validation error", err)
+	}
+	var out []string
+	for _, f := range apiErr.Fields {
+		out = append(out, f.Field+":"+f.Code)
+	}
+	sort.Strings(out)
+	return out
+}
+
+type validationCase struct {
+	name  string
+	value interface{}
+	want  []string
+}
+
+func runCases(t *testing.T, tests []validationCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failures(t, Struct(tt.value)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("failures = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func datePtr(y int, m time.Month, d int) *time.Time {
+	t := date(y, m, d)
+	return &t
+}
+
+func TestPatient(t *testing.T) {
+	dob := date(1990, 6, 15)
+	patient := func(edit func(*models.Patient)) models.Patient {
+		p := models.Patient{
+			Name:       "Asha Rao",
+			Phone:      "+919876543210",
+			Email:      "asha@example.com",
+			Gender:     "female",
+			DOB:        dob,
+			Age:        utils.AgeAt(dob, time.Now()),
+			Occupation: "Teacher",
+			Language:   "Kannada",
+		}
+		if edit != nil {
+			edit(&p)
+		}
+		return p
+	}
+	future := time.Now().AddDate(1, 0, 0)
+
+	runCases(t, []validationCase{
+		{"valid", patient(nil), nil},
+		// The age derived from a future DOB is negative, which age's own
+		// bounds reject as well.
+		{"dob in the future", patient(func(p *models.Patient) { p.DOB = future; p.Age = utils.AgeAt(future, time.Now()) }),
+			[]string{"age:gte", "dob:notfuture"}},
+		{"age does not match dob", patient(func(p *models.Patient) { p.Age++ }), []string{"age:age_dob"}},
+		{"enum compared case-insensitively", patient(func(p *models.Patient) { p.Gender = "FEMALE"; p.Status = "Admitted" }), nil},
+		{"unknown gender", patient(func(p *models.Patient) { p.Gender = "robot" }), []string{"gender:gender"}},
+		{"unknown status", patient(func(p *models.Patient) { p.Status = "asleep" }), []string{"status:patient_status"}},
+	})
+}
+
+func TestProblemDates(t *testing.T) {
+	runCases(t, []validationCase{
+		{"active without dates", models.PatientProblem{Description: "Asthma"}, nil},
+		{"resolved after onset", models.PatientProblem{Description: "Flu", Status: "RESOLVED",
+			OnsetDate: datePtr(2025, 1, 2), ResolvedDate: datePtr(2025, 1, 9)}, nil},
+		{"resolved without a date", models.PatientProblem{Description: "Flu", Status: "resolved"},
+			[]string{"resolved_date:required_if_resolved"}},
+		{"resolved before onset", models.PatientProblem{Description: "Flu", Status: "resolved",
+			OnsetDate: datePtr(2025, 1, 9), ResolvedDate: datePtr(2025, 1, 2)}, []string{"resolved_date:after_onset"}},
+		{"onset in the future", models.PatientProblem{Description: "Flu", OnsetDate: datePtr(time.Now().Year()+1, 1, 1)},
+			[]string{"onset_date:notfuture"}},
+	})
+}
+
+func TestScheduleHours(t *testing.T) {
+	runCases(t, []validationCase{
+		{"morning clinic", models.DoctorSchedule{Weekday: 1, StartTime: "09:00", EndTime: "13:00"}, nil},
+		{"end before start", models.DoctorSchedule{Weekday: 1, StartTime: "13:00", EndTime: "09:00"},
+			[]string{"end_time:after_start_time"}},
+		{"end equals start", models.DoctorSchedule{Weekday: 1, StartTime: "09:00", EndTime: "09:00"},
+			[]string{"end_time:after_start_time"}},
+		{"unparseable time left to its tag", models.DoctorSchedule{Weekday: 1, StartTime: "09:00", EndTime: "1pm"},
+			[]string{"end_time:datetime"}},
+		{"valid dates in order", models.DoctorSchedule{Weekday: 1, StartTime: "09:00", EndTime: "13:00",
+			ValidFrom: datePtr(2026, 1, 1), ValidTo: datePtr(2026, 6, 30)}, nil},
+		{"valid_to before valid_from", models.DoctorSchedule{Weekday: 1, StartTime: "09:00", EndTime: "13:00",
+			ValidFrom: datePtr(2026, 6, 30), ValidTo: datePtr(2026, 1, 1)}, []string{"valid_to:after_valid_from"}},
+	})
+}
+
+func TestOverrideHours(t *testing.T) {
+	runCases(t, []validationCase{
+		{"shortened day", models.DoctorScheduleOverride{Date: date(2026, 3, 2), StartTime: "09:00", EndTime: "11:00"}, nil},
+		{"closed day needs no hours", models.DoctorScheduleOverride{Date: date(2026, 3, 2), Closed: true}, nil},
+		{"end before start", models.DoctorScheduleOverride{Date: date(2026, 3, 2), StartTime: "11:00", EndTime: "09:00"},
+			[]string{"end_time:after_start_time"}},
+	})
+}
+
+func TestLeaveDates(t *testing.T) {
+	runCases(t, []validationCase{
+		{"one day", models.DoctorLeave{StartsOn: date(2026, 3, 2), EndsOn: date(2026, 3, 2)}, nil},
+		{"ends before it starts", models.DoctorLeave{StartsOn: date(2026, 3, 9), EndsOn: date(2026, 3, 2)},
+			[]string{"ends_on:after_starts_on"}},
+		{"kind compared case-insensitively", models.DoctorLeave{StartsOn: date(2026, 3, 2), EndsOn: date(2026, 3, 3), Kind: "Sick"}, nil},
+	})
+}
+
+func TestWaitlistDates(t *testing.T) {
+	runCases(t, []validationCase{
+		{"range", models.WaitlistEntry{PID: 1, DID: 2, EarliestDate: date(2026, 3, 2), LatestDate: date(2026, 3, 9)}, nil},
+		{"latest before earliest", models.WaitlistEntry{PID: 1, DID: 2, EarliestDate: date(2026, 3, 9), LatestDate: date(2026, 3, 2)},
+			[]string{"latest_date:after_earliest_date"}},
+	})
+}
+
+func TestJoin(t *testing.T) {
+	a := apierror.Validation(apierror.FieldError{Field: "name", Code: "required"})
+	b := apierror.Validation(apierror.FieldError{Field: "contacts[0].linked_p_id", Code: "linked_patient"})
+
+	if err := Join(nil, nil); err != nil {
+		t.Errorf("Join(nil, nil) = %v, want nil", err)
+	}
+
+	var got *apierror.Error
+	if !errors.As(Join(a, nil, b), &got) || got.Code != apierror.CodeValidationFailed {
+		t.Fatalf("Join(a, nil, b) is not a validation error")
+	}
+	if len(got.Fields) != 2 || got.Fields[0].Field != "name" || got.Fields[1].Field != "contacts[0].linked_p_id" {
+		t.Errorf("Join(a, nil, b) fields = %+v", got.Fields)
+	}
+
+	internal := apierror.New(apierror.CodeInternal, "boom")
+	if err := Join(a, internal); err != internal {
+		t.Errorf("Join(a, internal) = %v, want the internal error", err)
+	}
+}